@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a durable key/value backend. Values are stored JSON encoded.
+type Store interface {
+	// Get decodes the value under key into v and returns when it expires.
+	Get(key string, v any) (expiresAt time.Time, found bool, err error)
+	Set(key string, v any, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// fileRecord is one line of the store file. Later lines win over earlier ones.
+type fileRecord struct {
+	Key   string          `json:"k"`
+	Value json.RawMessage `json:"v,omitempty"`
+	// ExpiresAt is in unix milliseconds
+	ExpiresAt int64 `json:"e,omitempty"`
+	Deleted   bool  `json:"d,omitempty"`
+}
+
+// Entry describes a live entry of a FileStore.
+type Entry struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Size      int       `json:"size"`
+}
+
+// FileStore is a Store kept in a single append-only file of JSON lines. The
+// whole index lives in memory; the file is replayed on open and rewritten by
+// Compact. It is pure Go, so it works in the scratch image.
+//
+// Only one process should write to a file at a time.
+type FileStore struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	w       *bufio.Writer
+	entries map[string]fileRecord
+	// records counts lines in the file, live or not, to decide on compaction
+	records int
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// maxRecordSize bounds one line of the file.
+const maxRecordSize = 1 << 20
+
+// OpenFileStore opens or creates the store at path.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:    path,
+		entries: make(map[string]fileRecord),
+		now:     time.Now,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.f = f
+	s.w = bufio.NewWriter(f)
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	now := s.now().UnixMilli()
+	for scanner.Scan() {
+		var rec fileRecord
+		// a torn last line after a crash is skipped, everything before it is kept
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil || rec.Key == "" {
+			continue
+		}
+		s.records++
+		if rec.Deleted || rec.ExpiresAt <= now {
+			delete(s.entries, rec.Key)
+			continue
+		}
+		s.entries[rec.Key] = rec
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) Get(key string, v any) (time.Time, bool, error) {
+	s.mu.Lock()
+	rec, ok := s.entries[key]
+	if ok && rec.ExpiresAt <= s.now().UnixMilli() {
+		delete(s.entries, key)
+		ok = false
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	if err := json.Unmarshal(rec.Value, v); err != nil {
+		return time.Time{}, false, fmt.Errorf("decoding cache entry %q: %v", key, err)
+	}
+	return time.UnixMilli(rec.ExpiresAt), true, nil
+}
+
+// Set stores v under key for ttl. A ttl <= 0 is a no-op.
+func (s *FileStore) Set(key string, v any, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := fileRecord{Key: key, Value: value, ExpiresAt: s.now().Add(ttl).UnixMilli()}
+	if err := s.append(rec); err != nil {
+		return err
+	}
+	s.entries[key] = rec
+	return s.maybeCompact()
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(key)
+}
+
+func (s *FileStore) delete(key string) error {
+	if _, ok := s.entries[key]; !ok {
+		return nil
+	}
+	if err := s.append(fileRecord{Key: key, Deleted: true}); err != nil {
+		return err
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+// Purge deletes every entry whose key starts with prefix and returns how many
+// were removed. An empty prefix purges everything.
+func (s *FileStore) Purge(prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key := range s.entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := s.delete(key); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Entries lists the live entries whose key starts with prefix, sorted by key.
+func (s *FileStore) Entries(prefix string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UnixMilli()
+	var out []Entry
+	for key, rec := range s.entries {
+		if !strings.HasPrefix(key, prefix) || rec.ExpiresAt <= now {
+			continue
+		}
+		out = append(out, Entry{Key: key, ExpiresAt: time.UnixMilli(rec.ExpiresAt), Size: len(rec.Value)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Compact rewrites the file with only the live entries.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+func (s *FileStore) compact() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	now := s.now().UnixMilli()
+	records := 0
+	for key, rec := range s.entries {
+		if rec.ExpiresAt <= now {
+			delete(s.entries, key)
+			continue
+		}
+		if err := writeRecord(w, rec); err != nil {
+			tmp.Close()
+			return err
+		}
+		records++
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.f.Close()
+	s.f = f
+	s.w = bufio.NewWriter(f)
+	s.records = records
+	return nil
+}
+
+// maybeCompact compacts once dead lines outnumber live entries by far.
+func (s *FileStore) maybeCompact() error {
+	if s.records < 1000 || s.records < 4*len(s.entries) {
+		return nil
+	}
+	return s.compact()
+}
+
+// Close flushes and closes the file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// append writes rec through to the file, so a killed container loses nothing.
+func (s *FileStore) append(rec fileRecord) error {
+	if err := writeRecord(s.w, rec); err != nil {
+		return err
+	}
+	s.records++
+	return s.w.Flush()
+}
+
+func writeRecord(w *bufio.Writer, rec fileRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	City string `json:"city"`
+}
+
+func TestFileStoreSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() returned an error: %v", err)
+	}
+	if err := s.Set("address:20541155", testValue{City: "Rio de Janeiro"}, time.Hour); err != nil {
+		t.Fatalf("Set() returned an error: %v", err)
+	}
+	s.Set("address:gone", testValue{}, time.Hour)
+	s.Delete("address:gone")
+	s.Set("weather:rio", testValue{City: "Rio"}, time.Hour)
+	s.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() returned an error on reopen: %v", err)
+	}
+	defer s.Close()
+
+	var v testValue
+	expiresAt, ok, err := s.Get("address:20541155", &v)
+	if err != nil || !ok || v.City != "Rio de Janeiro" {
+		t.Fatalf("Get() after reopen = %+v, %v, %v", v, ok, err)
+	}
+	if time.Until(expiresAt) <= 0 || time.Until(expiresAt) > time.Hour {
+		t.Errorf("Get() returned expiry %v", expiresAt)
+	}
+	if _, ok, _ := s.Get("address:gone", &v); ok {
+		t.Errorf("Get() returned a deleted entry after reopen")
+	}
+
+	if entries := s.Entries("address:"); len(entries) != 1 || entries[0].Key != "address:20541155" {
+		t.Errorf("Entries(address:) = %+v", entries)
+	}
+	if n, err := s.Purge("weather:"); n != 1 || err != nil {
+		t.Errorf("Purge(weather:) = %d, %v", n, err)
+	}
+	if entries := s.Entries(""); len(entries) != 1 {
+		t.Errorf("Entries() after purge = %+v", entries)
+	}
+}
+
+func TestFileStoreExpiryAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() returned an error: %v", err)
+	}
+	defer s.Close()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	s.Set("short", testValue{}, time.Minute)
+	s.Set("long", testValue{}, time.Hour)
+	for i := 0; i < 10; i++ {
+		s.Set("long", testValue{City: "overwritten"}, time.Hour)
+	}
+
+	now = now.Add(2 * time.Minute)
+	var v testValue
+	if _, ok, _ := s.Get("short", &v); ok {
+		t.Errorf("Get() returned an expired entry")
+	}
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() returned an error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("compacted file has %d lines, expected 1:\n%s", lines, data)
+	}
+
+	// writes keep working after the file was swapped
+	if err := s.Set("after", testValue{}, time.Hour); err != nil {
+		t.Fatalf("Set() after Compact() returned an error: %v", err)
+	}
+	if _, ok, _ := s.Get("after", &v); !ok {
+		t.Errorf("Get() missed an entry written after compaction")
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+type flightCall[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// FlightGroup coalesces concurrent calls for the same key into one execution
+// whose result or error is handed to every caller, singleflight style. The
+// zero value is ready to use.
+//
+// The shared call is not tied to the context of the caller that started it:
+// it keeps running while at least one caller still waits, and is cancelled
+// once all of them gave up.
+type FlightGroup[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[V]
+}
+
+// Do runs fn for key unless a call for key is already in flight, in which case
+// it waits for that call instead.
+func (g *FlightGroup[V]) Do(ctx context.Context, key string, fn func(context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[V])
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		// keep the values of ctx (deadlines aside) but not its cancellation
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &flightCall[V]{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// nobody is left to receive the result
+			c.cancel()
+			g.forget(key, c)
+		}
+		g.mu.Unlock()
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (g *FlightGroup[V]) run(ctx context.Context, key string, c *flightCall[V], fn func(context.Context) (V, error)) {
+	c.val, c.err = fn(ctx)
+	g.mu.Lock()
+	g.forget(key, c)
+	g.mu.Unlock()
+	c.cancel()
+	close(c.done)
+}
+
+// forget drops c from the in-flight calls unless a newer call replaced it.
+func (g *FlightGroup[V]) forget(key string, c *flightCall[V]) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
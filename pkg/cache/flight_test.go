@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	var g FlightGroup[string]
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "", errors.New("upstream failed")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = g.Do(context.Background(), "20541155", fn)
+		}(i)
+	}
+	// let every caller join the call before it finishes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("fn ran %d times, expected 1", calls)
+	}
+	for i, err := range errs {
+		if err == nil || err.Error() != "upstream failed" {
+			t.Errorf("caller %d got %v, expected the shared error", i, err)
+		}
+	}
+
+	// a finished call is not reused
+	v, err := g.Do(context.Background(), "20541155", func(ctx context.Context) (string, error) { return "fresh", nil })
+	if v != "fresh" || err != nil {
+		t.Errorf("Do() after the call finished = %q, %v", v, err)
+	}
+}
+
+func TestFlightGroupCallerCancellation(t *testing.T) {
+	var g FlightGroup[int]
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	results := make(chan error, 2)
+	go func() { _, err := g.Do(first, "k", fn); results <- err }()
+	<-started
+	go func() { _, err := g.Do(second, "k", fn); results <- err }()
+	time.Sleep(10 * time.Millisecond)
+
+	// the call survives the caller that started it
+	cancelFirst()
+	if err := <-results; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatalf("shared call was cancelled while a caller still waited")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// and stops once nobody waits anymore
+	cancelSecond()
+	<-results
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("shared call kept running after every caller left")
+	}
+}
@@ -0,0 +1,122 @@
+// Package cache holds the caches used in front of the upstream providers.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats are counters describing how a cache has been used.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Expired   uint64 `json:"expired"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+}
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// LRU is a size bounded, least recently used cache whose entries expire after
+// a per entry TTL. It is safe for concurrent use.
+type LRU[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	stats    Stats
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewLRU builds an LRU holding at most capacity entries.
+func NewLRU[V any](capacity int) *LRU[V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU[V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value stored under key if it has not expired.
+func (c *LRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return zero, false
+	}
+	entry := el.Value.(*lruEntry[V])
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(el)
+		c.stats.Expired++
+		c.stats.Misses++
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	c.stats.Hits++
+	return entry.value, true
+}
+
+// Set stores value under key for ttl, evicting the least recently used entry
+// when the cache is full. A ttl <= 0 is a no-op.
+func (c *LRU[V]) Set(key string, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Delete drops key from the cache.
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *LRU[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Size = c.ll.Len()
+	s.Capacity = c.capacity
+	return s
+}
+
+func (c *LRU[V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruEntry[V]).key)
+}
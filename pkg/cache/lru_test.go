@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[int](2)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	// touching a makes b the least recently used
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) found an entry that should have been evicted")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %v, %v", v, ok)
+	}
+
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 1 || s.Evictions != 1 || s.Size != 2 || s.Capacity != 2 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string](10)
+	c.now = func() time.Time { return now }
+
+	c.Set("cep", "address", time.Minute)
+	c.Set("ignored", "value", 0)
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("cep"); !ok {
+		t.Errorf("Get() missed an entry before its TTL")
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Get("cep"); ok {
+		t.Errorf("Get() returned an expired entry")
+	}
+	if _, ok := c.Get("ignored"); ok {
+		t.Errorf("Set() stored an entry with a zero TTL")
+	}
+
+	if s := c.Stats(); s.Expired != 1 || s.Size != 0 {
+		t.Errorf("Stats() = %+v", s)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cache"
+)
+
+// envCacheFile enables the persistent cache of the server and is the default
+// file of the cache command.
+const envCacheFile = "CACHE_FILE"
+
+const cacheUsage = `usage: tempByCep cache [-file path] <command> [prefix]
+
+commands:
+  list [prefix]    print the live entries, optionally only keys with prefix (e.g. address:)
+  purge [prefix]   delete the entries with prefix, or every entry
+  compact          rewrite the file dropping expired and deleted entries
+
+Stop the server before purging or compacting a file it is using.
+`
+
+// runCacheCommand implements the "cache" sub command and returns the exit code.
+func runCacheCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, cacheUsage) }
+	file := fs.String("file", os.Getenv(envCacheFile), "cache file, defaults to $"+envCacheFile)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *file == "" {
+		fs.Usage()
+		return 2
+	}
+	prefix := fs.Arg(1)
+
+	store, err := cache.OpenFileStore(*file)
+	if err != nil {
+		fmt.Fprintf(stderr, "opening %s: %v\n", *file, err)
+		return 1
+	}
+	defer store.Close()
+
+	switch fs.Arg(0) {
+	case "list":
+		enc := json.NewEncoder(stdout)
+		for _, entry := range store.Entries(prefix) {
+			enc.Encode(entry)
+		}
+	case "purge":
+		n, err := store.Purge(prefix)
+		if err != nil {
+			fmt.Fprintf(stderr, "purging: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "purged %d entries\n", n)
+	case "compact":
+		if err := store.Compact(); err != nil {
+			fmt.Fprintf(stderr, "compacting: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%d live entries\n", len(store.Entries("")))
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package cepindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CSVOptions describes a CEP dump.
+type CSVOptions struct {
+	// Comma is the field delimiter. Zero detects it from the first line
+	// among ',', ';', tab, '|' and '@' (the separator of the DNE files).
+	Comma rune
+	// Columns names the fields of a file without a header line, e.g.
+	// "cep,logradouro,bairro,cidade,uf". Unknown names are ignored.
+	Columns []string
+	// Latin1 decodes the file as ISO-8859-1, as the Correios ship the DNE.
+	Latin1 bool
+}
+
+// columnAliases maps the header names found in common dumps to the fields of
+// a Record. Names are compared lower case and without accents.
+var columnAliases = map[string]string{
+	"cep": "cep", "zipcode": "cep", "zip": "cep", "codigo_postal": "cep",
+	"uf": "state", "estado": "state", "state": "state", "sigla_uf": "state",
+	"cidade": "city", "localidade": "city", "city": "city", "municipio": "city",
+	"bairro": "neighborhood", "neighborhood": "neighborhood", "district": "neighborhood",
+	"logradouro": "street", "street": "street", "endereco": "street", "address": "street", "rua": "street",
+}
+
+// ReadCSV reads a CEP dump and hands every row to add, which reports whether
+// it accepted the record. It returns how many rows were accepted and skipped.
+func ReadCSV(r io.Reader, opts CSVOptions, add func(Record) bool) (added int, skipped int, err error) {
+	if opts.Latin1 {
+		r = charmap.ISO8859_1.NewDecoder().Reader(r)
+	}
+	br := bufio.NewReaderSize(r, 64*1024)
+	comma := opts.Comma
+	if comma == 0 {
+		comma = detectComma(br)
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		header, err := cr.Read()
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading header: %v", err)
+		}
+		columns = header
+	}
+	positions := make(map[string]int)
+	for i, name := range columns {
+		name = strings.ToLower(strings.TrimSpace(utils.RemoveAccents(strings.TrimPrefix(name, "\ufeff"))))
+		if field, ok := columnAliases[name]; ok {
+			if _, seen := positions[field]; !seen {
+				positions[field] = i
+			}
+		}
+	}
+	if _, ok := positions["cep"]; !ok {
+		return 0, 0, errors.New("no cep column found, name the columns of the file")
+	}
+
+	get := func(row []string, field string) string {
+		i, ok := positions[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return added, skipped, nil
+		}
+		if err != nil {
+			return added, skipped, err
+		}
+		record := Record{
+			Cep:          get(row, "cep"),
+			State:        strings.ToUpper(get(row, "state")),
+			City:         get(row, "city"),
+			Neighborhood: get(row, "neighborhood"),
+			Street:       get(row, "street"),
+		}
+		if add(record) {
+			added++
+		} else {
+			skipped++
+		}
+	}
+}
+
+// detectComma picks the most frequent candidate delimiter of the first line.
+func detectComma(br *bufio.Reader) rune {
+	line, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	best, count := ',', 0
+	for _, c := range []rune{',', ';', '\t', '|', '@'} {
+		if n := bytes.Count(line, []byte(string(c))); n > count {
+			best, count = c, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,260 @@
+// Package cepindex stores CEP addresses in a compact, read-only local index so
+// lookups can be answered without any network call.
+//
+// The file starts with a magic string and two counts, followed by a table of
+// unique strings and by fixed size records sorted by CEP:
+//
+//	magic "TBCEPIX1"
+//	records uint32, strings uint32 (little endian)
+//	strings: uvarint length + bytes, each
+//	records: cep, state, city, neighborhood, street as uint32, each
+//
+// Cities, neighborhoods and street names repeat a lot, so interning them
+// keeps a full national dump small.
+package cepindex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const magic = "TBCEPIX1"
+
+// recordSize is the size of one record: the CEP and four string ids.
+const recordSize = 5 * 4
+
+// Record is one address of the index.
+type Record struct {
+	Cep          string
+	State        string
+	City         string
+	Neighborhood string
+	Street       string
+}
+
+// ErrInvalidIndex is returned when a file is not an index or is truncated.
+var ErrInvalidIndex = errors.New("invalid cep index")
+
+// Builder collects records and writes them as an index. Later records for
+// the same CEP replace earlier ones.
+type Builder struct {
+	records map[uint32]Record
+}
+
+func NewBuilder() *Builder {
+	return &Builder{records: make(map[uint32]Record)}
+}
+
+// Add stores r and reports false when its CEP is not 8 digits, once the
+// separators are removed.
+func (b *Builder) Add(r Record) bool {
+	cep, ok := parseCep(r.Cep)
+	if !ok {
+		return false
+	}
+	r.Cep = fmt.Sprintf("%08d", cep)
+	b.records[cep] = r
+	return true
+}
+
+// Len returns how many distinct CEPs were added.
+func (b *Builder) Len() int {
+	return len(b.records)
+}
+
+// WriteTo writes the index to w.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	ceps := make([]uint32, 0, len(b.records))
+	for cep := range b.records {
+		ceps = append(ceps, cep)
+	}
+	sort.Slice(ceps, func(i, j int) bool { return ceps[i] < ceps[j] })
+
+	ids := make(map[string]uint32)
+	var table []string
+	intern := func(s string) uint32 {
+		id, ok := ids[s]
+		if !ok {
+			id = uint32(len(table))
+			ids[s] = id
+			table = append(table, s)
+		}
+		return id
+	}
+	records := make([]byte, 0, len(ceps)*recordSize)
+	for _, cep := range ceps {
+		r := b.records[cep]
+		records = binary.LittleEndian.AppendUint32(records, cep)
+		for _, s := range []string{r.State, r.City, r.Neighborhood, r.Street} {
+			records = binary.LittleEndian.AppendUint32(records, intern(s))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	cw.Write([]byte(magic))
+	cw.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(ceps))))
+	cw.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(table))))
+	for _, s := range table {
+		cw.Write(binary.AppendUvarint(nil, uint64(len(s))))
+		cw.Write([]byte(s))
+	}
+	cw.Write(records)
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+// WriteFile writes the index to path through a temporary file, so a reader
+// never sees a half written index.
+func (b *Builder) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cepindex-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := b.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	// CreateTemp makes the file private, an index is meant to be shared
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Index is a loaded index. It is read-only and safe for concurrent use.
+type Index struct {
+	strings []string
+	records []byte
+}
+
+// Open loads the index stored at path.
+func Open(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse loads an index from its encoded form.
+func Parse(data []byte) (*Index, error) {
+	if len(data) < len(magic)+8 || string(data[:len(magic)]) != magic {
+		return nil, ErrInvalidIndex
+	}
+	data = data[len(magic):]
+	count := binary.LittleEndian.Uint32(data)
+	nstrings := binary.LittleEndian.Uint32(data[4:])
+	data = data[8:]
+	// every string takes at least its length byte, so a count the data cannot
+	// hold is a corrupt header, refused before sizing anything after it
+	if uint64(nstrings) > uint64(len(data)) {
+		return nil, ErrInvalidIndex
+	}
+
+	ix := &Index{strings: make([]string, 0, nstrings)}
+	for i := uint32(0); i < nstrings; i++ {
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return nil, ErrInvalidIndex
+		}
+		ix.strings = append(ix.strings, string(data[size:size+int(n)]))
+		data = data[size+int(n):]
+	}
+	if uint64(len(data)) != uint64(count)*recordSize {
+		return nil, ErrInvalidIndex
+	}
+	ix.records = data
+	for i := 0; i < ix.Len(); i++ {
+		ids := ix.ids(i)
+		for _, id := range ids[1:] {
+			if id >= nstrings {
+				return nil, ErrInvalidIndex
+			}
+		}
+	}
+	return ix, nil
+}
+
+// Len returns how many CEPs the index holds.
+func (ix *Index) Len() int {
+	return len(ix.records) / recordSize
+}
+
+func (ix *Index) ids(i int) [5]uint32 {
+	var ids [5]uint32
+	rec := ix.records[i*recordSize : (i+1)*recordSize]
+	for j := range ids {
+		ids[j] = binary.LittleEndian.Uint32(rec[j*4:])
+	}
+	return ids
+}
+
+// Lookup returns the address of cep, with or without separator.
+func (ix *Index) Lookup(cep string) (Record, bool) {
+	key, ok := parseCep(cep)
+	if !ok {
+		return Record{}, false
+	}
+	i := sort.Search(ix.Len(), func(i int) bool {
+		return binary.LittleEndian.Uint32(ix.records[i*recordSize:]) >= key
+	})
+	if i == ix.Len() {
+		return Record{}, false
+	}
+	ids := ix.ids(i)
+	if ids[0] != key {
+		return Record{}, false
+	}
+	return Record{
+		Cep:          fmt.Sprintf("%08d", key),
+		State:        ix.strings[ids[1]],
+		City:         ix.strings[ids[2]],
+		Neighborhood: ix.strings[ids[3]],
+		Street:       ix.strings[ids[4]],
+	}, true
+}
+
+// parseCep accepts 8 digits with optional "-" or "." separators.
+func parseCep(cep string) (uint32, bool) {
+	cep = strings.NewReplacer("-", "", ".", "", " ", "").Replace(cep)
+	if len(cep) != 8 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(cep, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
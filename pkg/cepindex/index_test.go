@@ -0,0 +1,109 @@
+package cepindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestBuildAndLookup(t *testing.T) {
+	dump := "CEP;Logradouro;Bairro;Cidade;UF\n" +
+		"20541-155;Rua Paula Brito;Andaraí;Rio de Janeiro;rj\n" +
+		"01001000;Praça da Sé;Sé;São Paulo;SP\n" +
+		"123;broken;;;\n" +
+		// later rows win
+		"20541155;Rua Paula Brito;Andaraí;Rio de Janeiro;RJ\n"
+
+	b := NewBuilder()
+	added, skipped, err := ReadCSV(strings.NewReader(dump), CSVOptions{}, b.Add)
+	if err != nil {
+		t.Fatalf("ReadCSV() returned an error: %v", err)
+	}
+	if added != 3 || skipped != 1 || b.Len() != 2 {
+		t.Errorf("ReadCSV() added %d, skipped %d, %d ceps", added, skipped, b.Len())
+	}
+
+	path := filepath.Join(t.TempDir(), "ceps.idx")
+	if err := b.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() returned an error: %v", err)
+	}
+	ix, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() returned an error: %v", err)
+	}
+	if ix.Len() != 2 {
+		t.Errorf("Len() = %d", ix.Len())
+	}
+
+	r, ok := ix.Lookup("20541-155")
+	expected := Record{Cep: "20541155", State: "RJ", City: "Rio de Janeiro", Neighborhood: "Andaraí", Street: "Rua Paula Brito"}
+	if !ok || r != expected {
+		t.Errorf("Lookup() = %+v, %v", r, ok)
+	}
+	if r, ok := ix.Lookup("01001000"); !ok || r.City != "São Paulo" {
+		t.Errorf("Lookup() = %+v, %v", r, ok)
+	}
+	for _, cep := range []string{"01001001", "99999999", "00000000", "abc"} {
+		if _, ok := ix.Lookup(cep); ok {
+			t.Errorf("Lookup(%q) found a record", cep)
+		}
+	}
+}
+
+func TestReadCSVWithoutHeaderLatin1(t *testing.T) {
+	line, err := charmap.ISO8859_1.NewEncoder().String("20541155@Rua Paula Brito@Andaraí@Rio de Janeiro@RJ\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder()
+	opts := CSVOptions{Columns: []string{"cep", "logradouro", "bairro", "cidade", "uf"}, Latin1: true}
+	if _, _, err := ReadCSV(strings.NewReader(line), opts, b.Add); err != nil {
+		t.Fatalf("ReadCSV() returned an error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() returned an error: %v", err)
+	}
+	ix, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	if r, _ := ix.Lookup("20541155"); r.Neighborhood != "Andaraí" || r.State != "RJ" {
+		t.Errorf("Lookup() = %+v", r)
+	}
+
+	// a truncated file is refused
+	if _, err := Parse(buf.Bytes()[:buf.Len()-1]); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Parse() of a truncated index returned %v", err)
+	}
+	if _, _, err := ReadCSV(strings.NewReader("a,b\n1,2\n"), CSVOptions{}, b.Add); err == nil {
+		t.Errorf("ReadCSV() accepted a file without a cep column")
+	}
+}
+
+func TestParseCorruptHeader(t *testing.T) {
+	for _, nstrings := range []uint32{1 << 24, 1<<32 - 1} {
+		data := []byte(magic)
+		data = binary.LittleEndian.AppendUint32(data, 0)
+		data = binary.LittleEndian.AppendUint32(data, nstrings)
+		data = append(data, 3, 'a', 'b', 'c')
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Parse(data)
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("Parse() of a header with %d strings returned %v", nstrings, err)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("Parse() of a header with %d strings allocated %d bytes", nstrings, allocated)
+		}
+	}
+}
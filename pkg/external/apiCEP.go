@@ -0,0 +1,67 @@
+package external
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const apiCepBaseUrl = "https://ws.apicep.com/cep/"
+
+// AddressDataApiCep is the answer of ApiCEP. Failures may come with HTTP 200
+// and the real status in the body, e.g. {"status":404,"ok":false}.
+type AddressDataApiCep struct {
+	Status     int    `json:"status"`
+	Ok         bool   `json:"ok"`
+	StatusText string `json:"statusText"`
+	Message    string `json:"message"`
+
+	Cep          string `json:"code"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"district"`
+	Street       string `json:"address"`
+}
+
+// ApiCep looks cep up on ApiCEP using DefaultClient.
+func ApiCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().ApiCep(ctx, cep)
+}
+
+func (c *Client) ApiCep(ctx context.Context, cep string) (Address, error) {
+	if err := utils.ValidateCep(cep); err != nil {
+		return Address{}, utils.InvalidZipError
+	}
+
+	var data AddressDataApiCep
+	if err := c.getCep(ctx, "ApiCEP", c.apiCepUrl+cep+".json", &data); err != nil {
+		return Address{}, err
+	}
+
+	switch data.Status {
+	case 0, http.StatusOK:
+	case http.StatusNotFound:
+		return Address{}, utils.ZipNotFoundError
+	case http.StatusBadRequest:
+		return Address{}, utils.InvalidZipError
+	case http.StatusTooManyRequests:
+		return Address{}, utils.RateLimitedError.WithMessage("ApiCEP rate limit reached")
+	default:
+		return Address{}, utils.UpstreamError.WithMessage("ApiCEP returned status " + http.StatusText(data.Status) + ": " + data.Message)
+	}
+
+	address := Address{
+		Cep:          data.Cep,
+		State:        data.State,
+		City:         data.City,
+		Neighborhood: data.Neighborhood,
+		Street:       data.Street,
+	}
+	//empty struct = valid format but no data
+	if address.isEmpty() {
+		return Address{}, utils.ZipNotFoundError
+	}
+	address.Source = "ApiCEP"
+	return address, nil
+}
@@ -0,0 +1,122 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoWeatherApiKey is returned by WeatherAPI calls on a Client without keys.
+var ErrNoWeatherApiKey = errors.New("no WeatherAPI key configured")
+
+// Environment variables holding WeatherAPI keys, read by OptionsFromEnv.
+const (
+	EnvWeatherApiKey     = "WEATHERAPI_KEY"
+	EnvWeatherApiKeys    = "WEATHERAPI_KEYS"
+	EnvWeatherApiKeyFile = "WEATHERAPI_KEY_FILE"
+)
+
+// KeyRing holds a list of API keys and hands out the current one. When the
+// upstream rejects a key the ring moves on to the next. It is safe for
+// concurrent use.
+type KeyRing struct {
+	mu      sync.Mutex
+	keys    []string
+	current int
+}
+
+// NewKeyRing builds a KeyRing, skipping blank and duplicated keys.
+func NewKeyRing(keys ...string) *KeyRing {
+	k := &KeyRing{}
+	k.add(keys...)
+	return k
+}
+
+func (k *KeyRing) add(keys ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || k.contains(key) {
+			continue
+		}
+		k.keys = append(k.keys, key)
+	}
+}
+
+func (k *KeyRing) contains(key string) bool {
+	for _, existing := range k.keys {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns how many keys the ring holds.
+func (k *KeyRing) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.keys)
+}
+
+// Current returns the key that should be used for the next request.
+func (k *KeyRing) Current() string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) == 0 {
+		return ""
+	}
+	return k.keys[k.current]
+}
+
+// Rotate moves past failed. It is a no-op when another caller already rotated
+// away from it, so concurrent failures of the same key rotate only once.
+func (k *KeyRing) Rotate(failed string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) == 0 || k.keys[k.current] != failed {
+		return
+	}
+	k.current = (k.current + 1) % len(k.keys)
+}
+
+// shouldRotateKey reports whether a WeatherAPI status means the key itself was
+// refused: invalid or disabled (401/403) or over its quota (403/429).
+func shouldRotateKey(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusTooManyRequests
+}
+
+// WithWeatherApiKeys adds keys to the Client's WeatherAPI key ring.
+func WithWeatherApiKeys(keys ...string) ClientOption {
+	return func(c *Client) error {
+		c.weatherKeys.add(keys...)
+		return nil
+	}
+}
+
+// WithWeatherApiKeyFile reads WeatherAPI keys from path, one per line, e.g. a
+// mounted secret. Blank lines and lines starting with # are ignored.
+func WithWeatherApiKeyFile(path string) ClientOption {
+	return func(c *Client) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading WeatherAPI key file: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			c.weatherKeys.add(line)
+		}
+		return nil
+	}
+}
+
+// HasWeatherApiKey reports whether at least one WeatherAPI key is configured.
+func (c *Client) HasWeatherApiKey() bool {
+	return c.weatherKeys.Len() > 0
+}
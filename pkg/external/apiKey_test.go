@@ -0,0 +1,97 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoRequestRotatesRefusedKeys(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		seen = append(seen, key)
+		switch key {
+		case "over-quota":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`))
+		case "good":
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithWeatherApiURL(srv.URL), WithWeatherApiKeys("invalid", "over-quota", "good"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	resp, err := c.doRequest(context.Background(), "GET", "current.json", map[string]string{"q": "London"})
+	if err != nil {
+		t.Fatalf("doRequest() returned an error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("doRequest() returned status %d, expected 200 after rotation", resp.StatusCode)
+	}
+	if len(seen) != 3 || seen[2] != "good" {
+		t.Errorf("doRequest() tried keys %v", seen)
+	}
+
+	// the ring stays on the working key
+	seen = nil
+	resp, err = c.doRequest(context.Background(), "GET", "current.json", map[string]string{"q": "London"})
+	if err != nil {
+		t.Fatalf("doRequest() returned an error: %v", err)
+	}
+	resp.Body.Close()
+	if len(seen) != 1 || seen[0] != "good" {
+		t.Errorf("doRequest() tried keys %v, expected only the good one", seen)
+	}
+}
+
+func TestDoRequestWithoutKey(t *testing.T) {
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	if c.HasWeatherApiKey() {
+		t.Fatalf("HasWeatherApiKey() is true without keys")
+	}
+	_, err = c.doRequest(context.Background(), "GET", "current.json", map[string]string{})
+	if !errors.Is(err, ErrNoWeatherApiKey) {
+		t.Errorf("doRequest() returned %v, expected %v", err, ErrNoWeatherApiKey)
+	}
+}
+
+func TestWeatherApiKeysFromEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(file, []byte("# mounted secret\nfile-key\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvWeatherApiKey, "env-key")
+	t.Setenv(EnvWeatherApiKeys, "list-a, list-b,env-key")
+	t.Setenv(EnvWeatherApiKeyFile, file)
+
+	c, err := NewClient(OptionsFromEnv()...)
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	if got := c.weatherKeys.Len(); got != 4 {
+		t.Errorf("key ring has %d keys, expected 4", got)
+	}
+	if got := c.weatherKeys.Current(); got != "env-key" {
+		t.Errorf("Current() = %q, expected env-key", got)
+	}
+
+	t.Setenv(EnvWeatherApiKeyFile, filepath.Join(t.TempDir(), "missing"))
+	if _, err := NewClient(OptionsFromEnv()...); err == nil {
+		t.Errorf("NewClient() accepted a missing key file")
+	}
+}
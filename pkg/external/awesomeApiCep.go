@@ -0,0 +1,62 @@
+package external
+
+import (
+	"context"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const awesomeApiBaseUrl = "https://cep.awesomeapi.com.br/json/"
+
+// AddressDataAwesomeApi is the answer of AwesomeAPI CEP. Errors come back as
+// 404 {"code":"not_found"} and 400 {"code":"invalid"}.
+type AddressDataAwesomeApi struct {
+	Cep string `json:"cep"`
+	// Address is the full street, AddressName the street without its type
+	Address     string `json:"address"`
+	AddressName string `json:"address_name"`
+	State       string `json:"state"`
+	District    string `json:"district"`
+	City        string `json:"city"`
+	CityIbge    string `json:"city_ibge"`
+	Ddd         string `json:"ddd"`
+	Latitude    string `json:"lat"`
+	Longitude   string `json:"lng"`
+}
+
+// AwesomeApiCep looks cep up on AwesomeAPI using DefaultClient.
+func AwesomeApiCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().AwesomeApiCep(ctx, cep)
+}
+
+func (c *Client) AwesomeApiCep(ctx context.Context, cep string) (Address, error) {
+	if err := utils.ValidateCep(cep); err != nil {
+		return Address{}, utils.InvalidZipError
+	}
+
+	var data AddressDataAwesomeApi
+	if err := c.getCep(ctx, "AwesomeAPI", c.awesomeApiUrl+cep, &data); err != nil {
+		return Address{}, err
+	}
+
+	street := data.Address
+	if street == "" {
+		street = data.AddressName
+	}
+	address := Address{
+		Cep:          data.Cep,
+		State:        data.State,
+		City:         data.City,
+		Neighborhood: data.District,
+		Street:       street,
+		Ibge:         data.CityIbge,
+		Ddd:          data.Ddd,
+		Location:     parseCoordinates(data.Latitude, data.Longitude),
+	}
+	//empty struct = valid format but no data
+	if address.isEmpty() {
+		return Address{}, utils.ZipNotFoundError
+	}
+	address.Source = "AwesomeAPI"
+	return address, nil
+}
@@ -3,10 +3,10 @@ package external
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/JonecoBoy/tempByCep/pkg/utils"
@@ -14,6 +14,9 @@ import (
 
 const requestExpirationTime = 10 * time.Second
 
+// v2 adds the coordinates of the CEP when they are known
+const brasilApiBaseUrl = "https://brasilapi.com.br/api/cep/v2/"
+
 type Address struct {
 	Cep          string `json:"cep"`
 	State        string `json:"state"`
@@ -21,6 +24,85 @@ type Address struct {
 	Neighborhood string `json:"neighborhood"`
 	Street       string `json:"street"`
 	Source       string `json:"source"`
+
+	// The fields below are only filled by the providers that know them.
+	Complement string `json:"complement,omitempty"`
+	// Ibge is the IBGE code of the city.
+	Ibge string `json:"ibge,omitempty"`
+	// Ddd is the telephone area code.
+	Ddd string `json:"ddd,omitempty"`
+	// Gia and Siafi are the city codes of the SP tax and federal budget systems.
+	Gia   string `json:"gia,omitempty"`
+	Siafi string `json:"siafi,omitempty"`
+	// StateName and Region are the full name of State and its region.
+	StateName string       `json:"state_name,omitempty"`
+	Region    string       `json:"region,omitempty"`
+	Location  *Coordinates `json:"location,omitempty"`
+
+	// FieldSources and Conflicts are only filled when several answers are
+	// merged: the provider each field came from, keyed by JSON field name,
+	// and the fields on which providers disagree.
+	FieldSources map[string]string `json:"field_sources,omitempty"`
+	Conflicts    []FieldConflict   `json:"conflicts,omitempty"`
+}
+
+// Coordinates is a point in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// parseCoordinates builds Coordinates from the text most providers send. It
+// returns nil when either value is missing or is not a number.
+func parseCoordinates(latitude string, longitude string) *Coordinates {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latitude), 64)
+	if err != nil {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(longitude), 64)
+	if err != nil {
+		return nil
+	}
+	return &Coordinates{Latitude: lat, Longitude: lon}
+}
+
+// FieldConflict holds the differing values providers gave for one field,
+// keyed by provider name.
+type FieldConflict struct {
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"`
+}
+
+// AddConflict records that provider answered value for field.
+func (a *Address) AddConflict(field string, provider string, value string) {
+	for i := range a.Conflicts {
+		if a.Conflicts[i].Field == field {
+			a.Conflicts[i].Values[provider] = value
+			return
+		}
+	}
+	a.Conflicts = append(a.Conflicts, FieldConflict{Field: field, Values: map[string]string{provider: value}})
+}
+
+// isEmpty reports whether a holds no address data at all.
+func (a Address) isEmpty() bool {
+	return a.Cep == "" && a.State == "" && a.City == "" && a.Neighborhood == "" && a.Street == ""
+}
+
+// AddressDataBrasilApi is the answer of BrasilAPI v2. The coordinates come
+// as text and the location is an empty object when they are unknown.
+type AddressDataBrasilApi struct {
+	Cep          string `json:"cep"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	Street       string `json:"street"`
+	Location     struct {
+		Coordinates struct {
+			Latitude  string `json:"latitude"`
+			Longitude string `json:"longitude"`
+		} `json:"coordinates"`
+	} `json:"location"`
 }
 
 type errorResponse struct {
@@ -29,27 +111,32 @@ type errorResponse struct {
 	Message string `json:"message"`
 }
 
-func BrasilApiCep(cep string) (Address, error) {
+// BrasilApiCep looks cep up on BrasilAPI using DefaultClient.
+func BrasilApiCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().BrasilApiCep(ctx, cep)
+}
+
+func (c *Client) BrasilApiCep(ctx context.Context, cep string) (Address, error) {
 	err := utils.ValidateCep(cep)
 	if err != nil {
 		return Address{}, utils.InvalidZipError
 	}
-	ctx := context.Background()
 	// o contexto expira em 1 segundo!
 	ctx, cancel := context.WithTimeout(ctx, requestExpirationTime)
 	defer cancel() // de alguma forma nosso contexto será cancelado
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://brasilapi.com.br/api/cep/v1/"+cep, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.brasilApiUrl+cep, nil)
 
 	if err != nil {
 		return Address{}, err
 	}
 
 	// faz a request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.do(req)
 
 	if err != nil {
 		return Address{}, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 
@@ -57,29 +144,35 @@ func BrasilApiCep(cep string) (Address, error) {
 			return Address{}, utils.ZipNotFoundError
 		}
 
-		return Address{}, errors.New("unkown error")
+		return Address{}, utils.UpstreamError.WithMessage("unkown error")
 
 	}
 
 	if ctx.Err() == context.DeadlineExceeded {
-		fmt.Println("Api fetch timeout exceeed.")
-		return Address{}, errors.New("api fetch timeout exceeed")
+		return Address{}, utils.TimeoutError.WithMessage("api fetch timeout exceeded")
 	}
 
 	// depois de tudo termina e faz o body
-	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return Address{}, err
 	}
-	var addressData Address
-	err = json.Unmarshal(body, &addressData)
+	var jsonData AddressDataBrasilApi
+	err = json.Unmarshal(body, &jsonData)
 	if err != nil {
 		return Address{}, err
 	}
+	addressData := Address{
+		Cep:          jsonData.Cep,
+		State:        jsonData.State,
+		City:         jsonData.City,
+		Neighborhood: jsonData.Neighborhood,
+		Street:       jsonData.Street,
+		Location:     parseCoordinates(jsonData.Location.Coordinates.Latitude, jsonData.Location.Coordinates.Longitude),
+	}
 
 	//empty struct = valid format but no data
-	if (addressData == Address{}) {
+	if addressData.isEmpty() {
 		return Address{}, utils.ZipNotFoundError
 	}
 
@@ -1,6 +1,8 @@
 package external
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,7 +12,7 @@ import (
 
 func TestViaCep(t *testing.T) {
 	cep := "20541155"
-	result, err := ViaCep(cep)
+	result, err := ViaCep(context.Background(), cep)
 	if err != nil {
 		t.Errorf("ViaCep() returned an error: %v", err)
 	}
@@ -34,11 +36,11 @@ func TestViaCep(t *testing.T) {
 
 func TestViaCepZipNotFound(t *testing.T) {
 	cep := "90541155"
-	_, err := ViaCep(cep)
+	_, err := ViaCep(context.Background(), cep)
 	if err == nil {
 		t.Fatalf("ViaCep() returned a value instead of an err: %v", err)
 	}
-	if err.Error() != utils.ZipNotFoundError.Error() {
+	if !errors.Is(err, utils.ErrNotFound) {
 		t.Errorf("ViaCep() did not return an " + utils.ZipNotFoundError.Error() + " zipcode error")
 	}
 
@@ -46,18 +48,18 @@ func TestViaCepZipNotFound(t *testing.T) {
 
 func TestViaCepInvalidFormat(t *testing.T) {
 	cep := "905411551"
-	_, err := ViaCep(cep)
+	_, err := ViaCep(context.Background(), cep)
 	if err == nil {
 		t.Fatalf("ViaCep() returned a value instead of an err: %v", err)
 	}
-	if err.Error() != utils.InvalidZipError.Error() {
+	if !errors.Is(err, utils.ErrInvalidCep) {
 		t.Errorf("ViaCep() did not return an " + utils.InvalidZipError.Error() + " zipcode error")
 	}
 }
 
 func TestBrasilApiCep(t *testing.T) {
 	cep := "20541155"
-	result, err := BrasilApiCep(cep)
+	result, err := BrasilApiCep(context.Background(), cep)
 	if err != nil {
 		t.Errorf("BrasilApiCep() returned an error: %v", err)
 	}
@@ -81,11 +83,11 @@ func TestBrasilApiCep(t *testing.T) {
 
 func TestBrasilApiCepZipNotFound(t *testing.T) {
 	cep := "90541155"
-	_, err := BrasilApiCep(cep)
+	_, err := BrasilApiCep(context.Background(), cep)
 	if err == nil {
 		t.Fatalf("BrasilApi() returned a value instead of an err: %v", err)
 	}
-	if err.Error() != utils.ZipNotFoundError.Error() {
+	if !errors.Is(err, utils.ErrNotFound) {
 		t.Errorf("BrasilApi() did not return an " + utils.ZipNotFoundError.Error() + " zipcode error")
 	}
 
@@ -93,11 +95,11 @@ func TestBrasilApiCepZipNotFound(t *testing.T) {
 
 func TestBrasilApiCepInvalidFormat(t *testing.T) {
 	cep := "90541A155"
-	_, err := BrasilApiCep(cep)
+	_, err := BrasilApiCep(context.Background(), cep)
 	if err == nil {
 		t.Fatalf("ViaCep() returned a value instead of an err: %v", err)
 	}
-	if err.Error() != utils.InvalidZipError.Error() {
+	if !errors.Is(err, utils.ErrInvalidCep) {
 		t.Errorf("BrasilApi() did not return an " + utils.InvalidZipError.Error() + " zipcode error")
 	}
 }
@@ -0,0 +1,214 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// fakeCepServer answers 20541155 with found, 99999999 as notFound says and
+// 55555555 with a 500.
+func fakeCepServer(t *testing.T, found string, notFound func(w http.ResponseWriter)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "20541155"):
+			w.Write([]byte(found))
+		case strings.Contains(r.URL.Path, "99999999"):
+			notFound(w)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCepProviders(t *testing.T) {
+	status404 := func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) }
+	cases := []struct {
+		name     string
+		option   func(string) ClientOption
+		provider func(*Client) CepProvider
+		found    string
+		notFound func(w http.ResponseWriter)
+		ibge     string
+	}{
+		{
+			name:     "OpenCEP",
+			option:   WithOpenCepURL,
+			provider: func(c *Client) CepProvider { return OpenCepProvider{Client: c} },
+			found:    `{"cep":"20541-155","logradouro":"Rua Paula Brito","bairro":"Andaraí","localidade":"Rio de Janeiro","uf":"RJ","ibge":"3304557"}`,
+			notFound: status404,
+			ibge:     "3304557",
+		},
+		{
+			name:     "AwesomeAPI",
+			option:   WithAwesomeApiURL,
+			provider: func(c *Client) CepProvider { return AwesomeApiProvider{Client: c} },
+			found:    `{"cep":"20541155","address_type":"Rua","address_name":"Paula Brito","address":"Rua Paula Brito","state":"RJ","district":"Andaraí","lat":"-22.92","lng":"-43.25","city":"Rio de Janeiro","city_ibge":"3304557","ddd":"21"}`,
+			notFound: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","message":"O CEP 99999999 nao foi encontrado"}`))
+			},
+			ibge: "3304557",
+		},
+		{
+			name:     "Postmon",
+			option:   WithPostmonURL,
+			provider: func(c *Client) CepProvider { return PostmonProvider{Client: c} },
+			found:    `{"bairro":"Andaraí","cidade":"Rio de Janeiro","logradouro":"Rua Paula Brito","estado_info":{"nome":"Rio de Janeiro"},"cidade_info":{"codigo_ibge":"3304557"},"cep":"20541155","estado":"RJ"}`,
+			notFound: status404,
+			ibge:     "3304557",
+		},
+		{
+			name:     "ApiCEP",
+			option:   WithApiCepURL,
+			provider: func(c *Client) CepProvider { return ApiCepProvider{Client: c} },
+			found:    `{"status":200,"ok":true,"code":"20541-155","state":"RJ","city":"Rio de Janeiro","district":"Andaraí","address":"Rua Paula Brito","statusText":"ok"}`,
+			// ApiCEP reports a missing CEP inside a 200
+			notFound: func(w http.ResponseWriter) {
+				w.Write([]byte(`{"status":404,"ok":false,"message":"CEP não encontrado","statusText":"not_found"}`))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := fakeCepServer(t, tc.found, tc.notFound)
+			c, err := NewClient(tc.option(srv.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+			if err != nil {
+				t.Fatalf("NewClient() returned an error: %v", err)
+			}
+			p := tc.provider(c)
+			if p.Name() != tc.name || !p.Capabilities().Has(CapLookup|CapRemote) {
+				t.Errorf("provider %q has capabilities %b", p.Name(), p.Capabilities())
+			}
+
+			address, err := p.Lookup(context.Background(), "20541155")
+			if err != nil {
+				t.Fatalf("Lookup() returned an error: %v", err)
+			}
+			if strings.ReplaceAll(address.Cep, "-", "") != "20541155" || address.State != "RJ" || address.City != "Rio de Janeiro" ||
+				address.Neighborhood != "Andaraí" || address.Street != "Rua Paula Brito" || address.Source != tc.name {
+				t.Errorf("Lookup() = %+v", address)
+			}
+			if address.Ibge != tc.ibge {
+				t.Errorf("Lookup() returned ibge %q, expected %q", address.Ibge, tc.ibge)
+			}
+
+			if _, err := p.Lookup(context.Background(), "99999999"); !errors.Is(err, utils.ErrNotFound) {
+				t.Errorf("Lookup() of an unknown CEP returned %v", err)
+			}
+			if _, err := p.Lookup(context.Background(), "55555555"); !errors.Is(err, utils.ErrUpstreamUnavailable) {
+				t.Errorf("Lookup() against a failing upstream returned %v", err)
+			}
+			if _, err := p.Lookup(context.Background(), "2054115"); !errors.Is(err, utils.ErrInvalidCep) {
+				t.Errorf("Lookup() of an invalid CEP returned %v", err)
+			}
+		})
+	}
+}
+
+func TestAddressDetails(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/20541155/json/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541-155","logradouro":"Rua Paula Brito","complemento":"até 417/418","bairro":"Andaraí","localidade":"Rio de Janeiro","uf":"RJ","estado":"Rio de Janeiro","regiao":"Sudeste","ibge":"3304557","gia":"","ddd":"21","siafi":"6001"}`))
+	})
+	mux.HandleFunc("/api/cep/v2/20541155", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541155","state":"RJ","city":"Rio de Janeiro","neighborhood":"Andaraí","street":"Rua Paula Brito","service":"open-cep","location":{"type":"Point","coordinates":{"longitude":"-43.2482","latitude":"-22.9257"}}}`))
+	})
+	mux.HandleFunc("/api/cep/v2/20541156", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541156","state":"RJ","city":"Rio de Janeiro","neighborhood":"Andaraí","street":"Rua Paula Brito","service":"viacep","location":{"type":"Point","coordinates":{}}}`))
+	})
+	mux.HandleFunc("/awesome/20541155", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541155","address":"Rua Paula Brito","state":"RJ","district":"Andaraí","lat":"-22.92","lng":"-43.25","city":"Rio de Janeiro","city_ibge":"3304557","ddd":"21"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	c, err := NewClient(WithViaCepURL(srv.URL+"/ws/"), WithBrasilApiURL(srv.URL+"/api/cep/v2/"), WithAwesomeApiURL(srv.URL+"/awesome/"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	via, err := c.ViaCep(context.Background(), "20541155")
+	if err != nil || via.Complement != "até 417/418" || via.Ibge != "3304557" || via.Ddd != "21" || via.Siafi != "6001" ||
+		via.Gia != "" || via.StateName != "Rio de Janeiro" || via.Region != "Sudeste" || via.Location != nil {
+		t.Errorf("ViaCep() = %+v, %v", via, err)
+	}
+
+	brasil, err := c.BrasilApiCep(context.Background(), "20541155")
+	if err != nil || brasil.Location == nil || brasil.Location.Latitude != -22.9257 || brasil.Location.Longitude != -43.2482 {
+		t.Errorf("BrasilApiCep() = %+v, %v", brasil, err)
+	}
+	// unknown coordinates come as an empty object
+	if brasil, err := c.BrasilApiCep(context.Background(), "20541156"); err != nil || brasil.Location != nil || brasil.Street != "Rua Paula Brito" {
+		t.Errorf("BrasilApiCep() without coordinates = %+v, %v", brasil, err)
+	}
+
+	awesome, err := c.AwesomeApiCep(context.Background(), "20541155")
+	if err != nil || awesome.Ddd != "21" || awesome.Location == nil || awesome.Location.Latitude != -22.92 {
+		t.Errorf("AwesomeApiCep() = %+v, %v", awesome, err)
+	}
+}
+
+func TestNewBuiltinProvider(t *testing.T) {
+	c, _ := NewClient()
+	for _, name := range BuiltinProviderNames() {
+		p, err := NewBuiltinProvider(strings.ToLower(name), c)
+		if err != nil || p.Name() != name {
+			t.Errorf("NewBuiltinProvider(%q) = %v, %v", name, p, err)
+		}
+	}
+	if _, err := NewBuiltinProvider("correios", c); err == nil {
+		t.Errorf("NewBuiltinProvider() accepted an unknown provider")
+	}
+}
+
+func TestViaCepSearch(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		switch {
+		case strings.Contains(r.URL.Path, "Frontin"):
+			w.Write([]byte(`[{"cep":"20260-010","logradouro":"Avenida Paulo de Frontin","bairro":"Rio Comprido","localidade":"Rio de Janeiro","uf":"RJ"}]`))
+		case strings.Contains(r.URL.Path, "Nada"):
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	c, err := NewClient(WithViaCepURL(srv.URL+"/ws/"), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	p := ViaCepProvider{Client: c}
+	if !p.Capabilities().Has(CapSearch) {
+		t.Errorf("ViaCepProvider cannot search")
+	}
+
+	addresses, err := p.Search(context.Background(), SearchQuery{State: "rj", City: "Rio de Janeiro", Street: "Paulo de Frontin"})
+	if err != nil || len(addresses) != 1 || addresses[0].Cep != "20260-010" || addresses[0].Source != "ViaCEP" {
+		t.Errorf("Search() = %+v, %v", addresses, err)
+	}
+	if path != "/ws/RJ/Rio%20de%20Janeiro/Paulo%20de%20Frontin/json/" {
+		t.Errorf("Search() requested %s", path)
+	}
+
+	if addresses, err := p.Search(context.Background(), SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Nada"}); err != nil || len(addresses) != 0 {
+		t.Errorf("Search() without matches = %+v, %v", addresses, err)
+	}
+	_, err = p.Search(context.Background(), SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Outra"})
+	if !errors.Is(err, utils.ErrInvalidQuery) || !strings.Contains(err.Error(), "ViaCEP returned status 400") {
+		t.Errorf("Search() rejected upstream returned %v", err)
+	}
+	if _, err := p.Search(context.Background(), SearchQuery{State: "RJ", City: "Rio", Street: "Av"}); !errors.Is(err, utils.ErrInvalidQuery) {
+		t.Errorf("Search() with a short street returned %v", err)
+	}
+}
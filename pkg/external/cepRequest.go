@@ -0,0 +1,44 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// getCep fetches url on behalf of provider and decodes a 200 answer into out.
+// Statuses are mapped to error kinds: 404 is not found, 400 an invalid CEP,
+// 429 rate limited and anything else an upstream failure.
+func (c *Client) getCep(ctx context.Context, provider string, url string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestExpirationTime)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return utils.ZipNotFoundError
+	case http.StatusBadRequest:
+		return utils.InvalidZipError.WithMessage(provider + " returned status " + resp.Status)
+	case http.StatusTooManyRequests:
+		return utils.RateLimitedError.WithMessage(provider + " rate limit reached")
+	default:
+		return utils.UpstreamError.WithMessage(provider + " returned status " + resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return utils.UpstreamError.WithMessage(provider + " returned an invalid body: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// Client performs every outbound call of this package. The zero value is not
+// usable, build one with NewClient.
+type Client struct {
+	httpClient *http.Client
+	// extra CA certificates (PEM) trusted on top of the system pool
+	caCerts [][]byte
+
+	// upstream endpoints, always ending with a slash
+	viaCepUrl     string
+	brasilApiUrl  string
+	openCepUrl    string
+	awesomeApiUrl string
+	postmonUrl    string
+	apiCepUrl     string
+	weatherApiUrl string
+
+	weatherKeys *KeyRing
+	// weatherLimiter throttles WeatherAPI calls, nil means unlimited
+	weatherLimiter *RateLimiter
+	retry          RetryPolicy
+	// configErr is why the environment could not configure DefaultClient.
+	// Every request of such a client fails with it.
+	configErr error
+}
+
+type ClientOption func(*Client) error
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     *Client
+)
+
+// DefaultClient returns the client used by the package level functions and
+// by providers built without a Client. It is configured from the environment
+// the first time it is needed, and TLS verification is always on. When the
+// environment is invalid the error is logged once and every request it sends
+// fails with it, see DefaultClientError.
+func DefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = clientFromEnv()
+	})
+	return defaultClient
+}
+
+func clientFromEnv() *Client {
+	c, err := NewClient(OptionsFromEnv()...)
+	if err != nil {
+		log.Printf("configuring the default http client from the environment: %v", err)
+		c, _ = NewClient()
+		c.configErr = fmt.Errorf("default http client is not configured: %w", err)
+	}
+	return c
+}
+
+// DefaultClientError returns why the environment could not configure
+// DefaultClient, or nil.
+func DefaultClientError() error {
+	return DefaultClient().configErr
+}
+
+// NewClient builds a Client. Without options it uses its own transport
+// cloned from http.DefaultTransport, with certificate verification enabled.
+func NewClient(opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		viaCepUrl:     viaCepBaseUrl,
+		brasilApiUrl:  brasilApiBaseUrl,
+		openCepUrl:    openCepBaseUrl,
+		awesomeApiUrl: awesomeApiBaseUrl,
+		postmonUrl:    postmonBaseUrl,
+		apiCepUrl:     apiCepBaseUrl,
+		weatherApiUrl: weatherApiBaseUrl,
+		weatherKeys:   NewKeyRing(),
+		retry:         DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+
+	if len(c.caCerts) > 0 {
+		rt := c.httpClient.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		transport, ok := rt.(*http.Transport)
+		if !ok {
+			return nil, errors.New("custom CA certificates require an *http.Transport")
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, pem := range c.caCerts {
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("no valid certificate found in CA bundle")
+			}
+		}
+		transport = transport.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+		hc := *c.httpClient
+		hc.Transport = transport
+		c.httpClient = &hc
+	}
+
+	return c, nil
+}
+
+// WithHTTPClient makes the Client send its requests through hc.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) error {
+		if hc == nil {
+			return errors.New("http client must not be nil")
+		}
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithTransport makes the Client send its requests through rt, e.g. a
+// corporate proxy or a test transport.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) error {
+		if rt == nil {
+			return errors.New("transport must not be nil")
+		}
+		c.httpClient = &http.Client{Transport: rt}
+		return nil
+	}
+}
+
+// WithCACerts trusts the PEM encoded certificates in addition to the system pool.
+func WithCACerts(pem []byte) ClientOption {
+	return func(c *Client) error {
+		c.caCerts = append(c.caCerts, pem)
+		return nil
+	}
+}
+
+// WithCACertFile trusts the PEM bundle stored at path in addition to the system pool.
+func WithCACertFile(path string) ClientOption {
+	return func(c *Client) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		c.caCerts = append(c.caCerts, pem)
+		return nil
+	}
+}
+
+// WithViaCepURL points ViaCEP lookups at base, e.g. a local stand-in or an
+// internal mirror. Requests go to base + cep + "/json/".
+func WithViaCepURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.viaCepUrl = u
+		return err
+	}
+}
+
+// WithBrasilApiURL points BrasilAPI lookups at base. Requests go to base + cep.
+func WithBrasilApiURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.brasilApiUrl = u
+		return err
+	}
+}
+
+// WithOpenCepURL points OpenCEP lookups at base. Requests go to base + cep.
+func WithOpenCepURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.openCepUrl = u
+		return err
+	}
+}
+
+// WithAwesomeApiURL points AwesomeAPI lookups at base. Requests go to base + cep.
+func WithAwesomeApiURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.awesomeApiUrl = u
+		return err
+	}
+}
+
+// WithPostmonURL points Postmon lookups at base. Requests go to base + cep.
+func WithPostmonURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.postmonUrl = u
+		return err
+	}
+}
+
+// WithApiCepURL points ApiCEP lookups at base. Requests go to
+// base + cep + ".json".
+func WithApiCepURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.apiCepUrl = u
+		return err
+	}
+}
+
+// WithWeatherApiURL points WeatherAPI calls at base. Requests go to
+// base + "current.json" and friends.
+func WithWeatherApiURL(base string) ClientOption {
+	return func(c *Client) error {
+		u, err := normalizeBaseUrl(base)
+		c.weatherApiUrl = u
+		return err
+	}
+}
+
+// Environment variables read by OptionsFromEnv.
+const (
+	EnvViaCepURL     = "VIACEP_URL"
+	EnvBrasilApiURL  = "BRASILAPI_URL"
+	EnvOpenCepURL    = "OPENCEP_URL"
+	EnvAwesomeApiURL = "AWESOMEAPI_URL"
+	EnvPostmonURL    = "POSTMON_URL"
+	EnvApiCepURL     = "APICEP_URL"
+	EnvWeatherApiURL = "WEATHERAPI_URL"
+	EnvCACertFile    = "CA_CERT_FILE"
+	// EnvRetryMaxAttempts overrides RetryPolicy.MaxAttempts, 1 disables retries.
+	EnvRetryMaxAttempts = "RETRY_MAX_ATTEMPTS"
+	// EnvWeatherApiRateLimit holds limits in the format of ParseRateLimits.
+	EnvWeatherApiRateLimit = "WEATHERAPI_RATE_LIMIT"
+	// EnvWeatherApiRateLimitWait overrides DefaultRateLimitWait.
+	EnvWeatherApiRateLimitWait = "WEATHERAPI_RATE_LIMIT_WAIT"
+)
+
+// OptionsFromEnv builds client options from the environment. Unset variables
+// keep the defaults.
+func OptionsFromEnv() []ClientOption {
+	var opts []ClientOption
+	if v := os.Getenv(EnvViaCepURL); v != "" {
+		opts = append(opts, WithViaCepURL(v))
+	}
+	if v := os.Getenv(EnvBrasilApiURL); v != "" {
+		opts = append(opts, WithBrasilApiURL(v))
+	}
+	if v := os.Getenv(EnvOpenCepURL); v != "" {
+		opts = append(opts, WithOpenCepURL(v))
+	}
+	if v := os.Getenv(EnvAwesomeApiURL); v != "" {
+		opts = append(opts, WithAwesomeApiURL(v))
+	}
+	if v := os.Getenv(EnvPostmonURL); v != "" {
+		opts = append(opts, WithPostmonURL(v))
+	}
+	if v := os.Getenv(EnvApiCepURL); v != "" {
+		opts = append(opts, WithApiCepURL(v))
+	}
+	if v := os.Getenv(EnvWeatherApiURL); v != "" {
+		opts = append(opts, WithWeatherApiURL(v))
+	}
+	if v := os.Getenv(EnvCACertFile); v != "" {
+		opts = append(opts, WithCACertFile(v))
+	}
+	if v := os.Getenv(EnvWeatherApiKey); v != "" {
+		opts = append(opts, WithWeatherApiKeys(v))
+	}
+	if v := os.Getenv(EnvWeatherApiKeys); v != "" {
+		opts = append(opts, WithWeatherApiKeys(strings.Split(v, ",")...))
+	}
+	if v := os.Getenv(EnvWeatherApiKeyFile); v != "" {
+		opts = append(opts, WithWeatherApiKeyFile(v))
+	}
+	if v := os.Getenv(EnvRetryMaxAttempts); v != "" {
+		opts = append(opts, func(c *Client) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", EnvRetryMaxAttempts, err)
+			}
+			c.retry.MaxAttempts = n
+			return nil
+		})
+	}
+	if v := os.Getenv(EnvWeatherApiRateLimit); v != "" {
+		opts = append(opts, func(c *Client) error {
+			limits, err := ParseRateLimits(v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", EnvWeatherApiRateLimit, err)
+			}
+			wait := DefaultRateLimitWait
+			if w := os.Getenv(EnvWeatherApiRateLimitWait); w != "" {
+				if wait, err = time.ParseDuration(w); err != nil {
+					return fmt.Errorf("%s: %v", EnvWeatherApiRateLimitWait, err)
+				}
+			}
+			return WithWeatherApiRateLimit(wait, limits...)(c)
+		})
+	}
+	return opts
+}
+
+func normalizeBaseUrl(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid base url %q: %v", base, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid base url %q: must be an absolute http(s) url", base)
+	}
+	return strings.TrimRight(base, "/") + "/", nil
+}
+
+// do sends req and classifies transport failures into error kinds, so callers
+// can tell a timeout from an unreachable upstream with errors.Is. Transient
+// failures are retried as the retry policy says, within the deadline of the
+// request context.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	return c.doLimited(req, nil)
+}
+
+// doLimited is do with every attempt, retries included, waiting for limiter.
+func (c *Client) doLimited(req *http.Request, limiter *RateLimiter) (*http.Response, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+	ctx := req.Context()
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 || !replayable(req) {
+		attempts = 1
+	}
+
+	r := req
+	for attempt := 1; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(r)
+		if err != nil {
+			err = classifyTransportError(err)
+		}
+		if attempt >= attempts || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		delay, ok := c.retry.backoff(attempt, resp)
+		if !ok || !fitsDeadline(ctx, delay) {
+			return resp, err
+		}
+		next, rewindErr := rewind(req)
+		if rewindErr != nil {
+			return resp, err
+		}
+		discard(resp)
+		r = next
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, classifyTransportError(ctx.Err())
+		}
+	}
+}
+
+func classifyTransportError(err error) error {
+	// the caller gave up, this says nothing about the upstream
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %w", utils.ErrTimeout, err)
+	}
+	return fmt.Errorf("%w: %w", utils.ErrUpstreamUnavailable, err)
+}
+
+// HTTPClient returns the underlying *http.Client.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+func clientOrDefault(c *Client) *Client {
+	if c == nil {
+		return DefaultClient()
+	}
+	return c
+}
@@ -0,0 +1,195 @@
+package external
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClientWithTransport(t *testing.T) {
+	var requested string
+	c, err := NewClient(WithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = r.URL.String()
+		return jsonResponse(http.StatusOK, `{"cep":"20541-155","uf":"RJ","localidade":"Rio de Janeiro","bairro":"Andaraí","logradouro":"Rua Paula Brito"}`), nil
+	})))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	result, err := c.ViaCep(context.Background(), "20541155")
+	if err != nil {
+		t.Fatalf("ViaCep() returned an error: %v", err)
+	}
+	if !strings.Contains(requested, "20541155") {
+		t.Errorf("ViaCep() requested %q through the transport", requested)
+	}
+	if result.City != "Rio de Janeiro" || result.Source != "ViaCEP" {
+		t.Errorf("ViaCep() returned unexpected address: %+v", result)
+	}
+}
+
+func TestClientVerifiesTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	if _, err := c.HTTPClient().Get(srv.URL); err == nil {
+		t.Errorf("default client accepted an untrusted certificate")
+	}
+
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	c, err = NewClient(WithCACerts(caPem))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	resp, err := c.HTTPClient().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("client with custom CA returned an error: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := NewClient(WithCACerts([]byte("not a certificate"))); err == nil {
+		t.Errorf("NewClient() accepted an invalid CA bundle")
+	}
+}
+
+func TestClientBaseUrls(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/20541155/json/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541-155","uf":"RJ","localidade":"Rio de Janeiro","bairro":"Andaraí","logradouro":"Rua Paula Brito"}`))
+	})
+	mux.HandleFunc("/api/cep/v1/20541155", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cep":"20541155","state":"RJ","city":"Rio de Janeiro","neighborhood":"Andaraí","street":"Rua Paula Brito"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c, err := NewClient(WithViaCepURL(srv.URL+"/ws"), WithBrasilApiURL(srv.URL+"/api/cep/v1/"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	via, err := c.ViaCep(context.Background(), "20541155")
+	if err != nil || via.Source != "ViaCEP" {
+		t.Errorf("ViaCep() against local server returned %+v, %v", via, err)
+	}
+	brasil, err := c.BrasilApiCep(context.Background(), "20541155")
+	if err != nil || brasil.Source != "brasilAPI" {
+		t.Errorf("BrasilApiCep() against local server returned %+v, %v", brasil, err)
+	}
+
+	if _, err := NewClient(WithWeatherApiURL("not a url")); err == nil {
+		t.Errorf("NewClient() accepted an invalid base url")
+	}
+}
+
+func TestDefaultClientConfigError(t *testing.T) {
+	t.Setenv(EnvWeatherApiKeyFile, filepath.Join(t.TempDir(), "missing"))
+	c := clientFromEnv()
+	if c.configErr == nil {
+		t.Fatalf("clientFromEnv() ignored an unreadable key file")
+	}
+	if _, err := c.ViaCep(context.Background(), "20541155"); !errors.Is(err, c.configErr) {
+		t.Errorf("ViaCep() on a misconfigured client returned %v", err)
+	}
+	if _, err := c.CurrentWeather(context.Background(), "-22.66,-43.02", "pt"); !errors.Is(err, c.configErr) {
+		t.Errorf("CurrentWeather() on a misconfigured client returned %v", err)
+	}
+	if DefaultClientError() != nil {
+		t.Errorf("DefaultClientError() = %v with a valid environment", DefaultClientError())
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv(EnvViaCepURL, "http://localhost:9999/ws")
+	c, err := NewClient(OptionsFromEnv()...)
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	if c.viaCepUrl != "http://localhost:9999/ws/" {
+		t.Errorf("OptionsFromEnv() set viaCepUrl to %q", c.viaCepUrl)
+	}
+	if c.brasilApiUrl != brasilApiBaseUrl {
+		t.Errorf("OptionsFromEnv() changed brasilApiUrl to %q", c.brasilApiUrl)
+	}
+}
+
+func TestClientErrorKinds(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "99999999"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(r.URL.Path, "55555555"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithBrasilApiURL(srv.URL))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	cases := []struct {
+		cep  string
+		kind error
+	}{
+		{"123", utils.ErrInvalidCep},
+		{"99999999", utils.ErrNotFound},
+		{"55555555", utils.ErrUpstreamUnavailable},
+	}
+	for _, tc := range cases {
+		_, err := c.BrasilApiCep(context.Background(), tc.cep)
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("BrasilApiCep(%s) returned %v, expected kind %v", tc.cep, err, tc.kind)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.BrasilApiCep(ctx, "20541155")
+	if !errors.Is(err, utils.ErrTimeout) {
+		t.Errorf("BrasilApiCep() past its deadline returned %v, expected kind %v", err, utils.ErrTimeout)
+	}
+
+	srv.Close()
+	_, err = c.BrasilApiCep(context.Background(), "20541155")
+	if !errors.Is(err, utils.ErrUpstreamUnavailable) {
+		t.Errorf("BrasilApiCep() against a closed server returned %v, expected kind %v", err, utils.ErrUpstreamUnavailable)
+	}
+
+	// HttpError matches by status and kind, not by message
+	if !errors.Is(utils.ZipNotFoundError.WithMessage("other message"), utils.ZipNotFoundError) {
+		t.Errorf("HttpError.Is() did not match a reworded ZipNotFoundError")
+	}
+}
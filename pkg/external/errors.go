@@ -0,0 +1,136 @@
+package external
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// ProviderError ties a lookup failure to the provider that produced it.
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProviderError) ProviderName() string {
+	return e.Provider
+}
+
+// HTTPStatus uses the status of the wrapped error, or of its error kind. Any
+// other failure (e.g. a bad payload) is an upstream failure.
+func (e *ProviderError) HTTPStatus() int {
+	status, _ := e.classify()
+	return status
+}
+
+func (e *ProviderError) ProblemCode() string {
+	_, code := e.classify()
+	return code
+}
+
+func (e *ProviderError) classify() (int, string) {
+	var detailer utils.ProblemDetailer
+	if errors.As(e.Err, &detailer) {
+		return detailer.HTTPStatus(), detailer.ProblemCode()
+	}
+	if status, code, ok := utils.KindStatus(e.Err); ok {
+		return status, code
+	}
+	return http.StatusBadGateway, "upstream_error"
+}
+
+// AggregateError is returned when every provider failed to resolve a CEP.
+type AggregateError struct {
+	Cep    string
+	Errors []error
+}
+
+// Error collapses identical messages so that, when every provider agrees
+// (e.g. the CEP does not exist), callers see a single clean message.
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 0 {
+		return "no provider answered for cep " + e.Cep
+	}
+	first := e.Errors[0].Error()
+	same := true
+	for _, err := range e.Errors[1:] {
+		if err.Error() != first {
+			same = false
+			break
+		}
+	}
+	if same {
+		return first
+	}
+
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		var perr *ProviderError
+		if errors.As(err, &perr) {
+			msgs = append(msgs, perr.Provider+": "+perr.Err.Error())
+			continue
+		}
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// decisive picks the error that best describes the whole lookup: a definitive
+// client side answer (e.g. not found) from any provider wins over upstream
+// failures of the others.
+func (e *AggregateError) decisive() utils.ProblemDetailer {
+	var first utils.ProblemDetailer
+	for _, err := range e.Errors {
+		var detailer utils.ProblemDetailer
+		if !errors.As(err, &detailer) {
+			continue
+		}
+		if status := detailer.HTTPStatus(); status >= 400 && status < 500 {
+			return detailer
+		}
+		if first == nil {
+			first = detailer
+		}
+	}
+	return first
+}
+
+func (e *AggregateError) HTTPStatus() int {
+	if d := e.decisive(); d != nil {
+		return d.HTTPStatus()
+	}
+	return http.StatusBadGateway
+}
+
+func (e *AggregateError) ProblemCode() string {
+	if d := e.decisive(); d != nil {
+		return d.ProblemCode()
+	}
+	return "upstream_error"
+}
+
+// ProviderName lists every provider that failed, comma separated.
+func (e *AggregateError) ProviderName() string {
+	names := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		var namer utils.ProviderNamer
+		if errors.As(err, &namer) {
+			names = append(names, namer.ProviderName())
+		}
+	}
+	return strings.Join(names, ",")
+}
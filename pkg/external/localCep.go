@@ -0,0 +1,40 @@
+package external
+
+import (
+	"context"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// LocalCepProvider answers lookups from a local index built by the
+// import-cep command, without any network call. An index is rarely complete,
+// so it works best next to remote providers, first or as a fallback.
+type LocalCepProvider struct {
+	Index *cepindex.Index
+}
+
+func (LocalCepProvider) Name() string { return "local" }
+
+func (p LocalCepProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	if err := utils.ValidateCep(cep); err != nil {
+		return Address{}, utils.InvalidZipError
+	}
+	if p.Index == nil {
+		return Address{}, utils.ZipNotFoundError
+	}
+	record, ok := p.Index.Lookup(cep)
+	if !ok {
+		return Address{}, utils.ZipNotFoundError
+	}
+	return Address{
+		Cep:          record.Cep,
+		State:        record.State,
+		City:         record.City,
+		Neighborhood: record.Neighborhood,
+		Street:       record.Street,
+		Source:       "local",
+	}, nil
+}
+
+func (LocalCepProvider) Capabilities() Capabilities { return CapLookup }
@@ -0,0 +1,52 @@
+package external
+
+import (
+	"context"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const openCepBaseUrl = "https://opencep.com/v1/"
+
+// AddressDataOpenCep is the answer of OpenCEP, modelled after ViaCEP.
+type AddressDataOpenCep struct {
+	Cep          string `json:"cep"`
+	State        string `json:"uf"`
+	City         string `json:"localidade"`
+	Neighborhood string `json:"bairro"`
+	Street       string `json:"logradouro"`
+	Complement   string `json:"complemento"`
+	Ibge         string `json:"ibge"`
+}
+
+// OpenCep looks cep up on OpenCEP using DefaultClient.
+func OpenCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().OpenCep(ctx, cep)
+}
+
+func (c *Client) OpenCep(ctx context.Context, cep string) (Address, error) {
+	if err := utils.ValidateCep(cep); err != nil {
+		return Address{}, utils.InvalidZipError
+	}
+
+	var data AddressDataOpenCep
+	if err := c.getCep(ctx, "OpenCEP", c.openCepUrl+cep, &data); err != nil {
+		return Address{}, err
+	}
+
+	address := Address{
+		Cep:          data.Cep,
+		State:        data.State,
+		City:         data.City,
+		Neighborhood: data.Neighborhood,
+		Street:       data.Street,
+		Complement:   data.Complement,
+		Ibge:         data.Ibge,
+	}
+	//empty struct = valid format but no data
+	if address.isEmpty() {
+		return Address{}, utils.ZipNotFoundError
+	}
+	address.Source = "OpenCEP"
+	return address, nil
+}
@@ -0,0 +1,59 @@
+package external
+
+import (
+	"context"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const postmonBaseUrl = "https://api.postmon.com.br/v1/cep/"
+
+// AddressDataPostmon is the answer of Postmon. An unknown CEP is a 404 with
+// an empty body.
+type AddressDataPostmon struct {
+	Cep          string `json:"cep"`
+	State        string `json:"estado"`
+	City         string `json:"cidade"`
+	Neighborhood string `json:"bairro"`
+	Street       string `json:"logradouro"`
+	Complement   string `json:"complemento"`
+	StateInfo    struct {
+		Name string `json:"nome"`
+	} `json:"estado_info"`
+	CityInfo struct {
+		Ibge string `json:"codigo_ibge"`
+	} `json:"cidade_info"`
+}
+
+// PostmonCep looks cep up on Postmon using DefaultClient.
+func PostmonCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().PostmonCep(ctx, cep)
+}
+
+func (c *Client) PostmonCep(ctx context.Context, cep string) (Address, error) {
+	if err := utils.ValidateCep(cep); err != nil {
+		return Address{}, utils.InvalidZipError
+	}
+
+	var data AddressDataPostmon
+	if err := c.getCep(ctx, "Postmon", c.postmonUrl+cep, &data); err != nil {
+		return Address{}, err
+	}
+
+	address := Address{
+		Cep:          data.Cep,
+		State:        data.State,
+		City:         data.City,
+		Neighborhood: data.Neighborhood,
+		Street:       data.Street,
+		Complement:   data.Complement,
+		Ibge:         data.CityInfo.Ibge,
+		StateName:    data.StateInfo.Name,
+	}
+	//empty struct = valid format but no data
+	if address.isEmpty() {
+		return Address{}, utils.ZipNotFoundError
+	}
+	address.Source = "Postmon"
+	return address, nil
+}
@@ -0,0 +1,240 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Capabilities is a set of flags describing what a CepProvider supports.
+type Capabilities uint
+
+const (
+	// CapLookup means the provider can resolve a single CEP into an Address.
+	CapLookup Capabilities = 1 << iota
+	// CapRemote means the provider needs an outbound network call to answer.
+	CapRemote
+	// CapSearch means the provider implements AddressSearcher.
+	CapSearch
+)
+
+// Has reports whether every flag in other is set in c.
+func (c Capabilities) Has(other Capabilities) bool {
+	return c&other == other
+}
+
+// CepProvider is a source of addresses that a lookup can fan out to.
+type CepProvider interface {
+	// Name identifies the provider inside a Registry and in Address.Source.
+	Name() string
+	Lookup(ctx context.Context, cep string) (Address, error)
+	Capabilities() Capabilities
+}
+
+// Registry keeps an ordered list of CEP providers. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	providers []CepProvider
+}
+
+// DefaultRegistry holds the public providers backed by DefaultClient.
+var DefaultRegistry = NewRegistry(BrasilApiProvider{}, ViaCepProvider{})
+
+func NewRegistry(providers ...CepProvider) *Registry {
+	r := &Registry{}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register appends p to the registry. A provider with the same name is
+// replaced in place, keeping its position.
+func (r *Registry) Register(p CepProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.providers {
+		if existing.Name() == p.Name() {
+			r.providers[i] = p
+			return
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// Remove drops the provider with the given name and reports whether it existed.
+func (r *Registry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, p := range r.providers {
+		if p.Name() == name {
+			r.providers = append(r.providers[:i], r.providers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the provider registered under name.
+func (r *Registry) Get(name string) (CepProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Reorder moves the named providers to the front, in the given order.
+// Unknown names are ignored and the remaining providers keep their order.
+func (r *Registry) Reorder(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ordered := make([]CepProvider, 0, len(r.providers))
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		for _, p := range r.providers {
+			if p.Name() == name && !used[name] {
+				ordered = append(ordered, p)
+				used[name] = true
+			}
+		}
+	}
+	for _, p := range r.providers {
+		if !used[p.Name()] {
+			ordered = append(ordered, p)
+		}
+	}
+	r.providers = ordered
+}
+
+// Providers returns a snapshot of the registered providers in order.
+func (r *Registry) Providers() []CepProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]CepProvider, len(r.providers))
+	copy(out, r.providers)
+	return out
+}
+
+// BrasilApiProvider exposes BrasilApiCep as a CepProvider. A nil Client
+// falls back to DefaultClient.
+type BrasilApiProvider struct {
+	Client *Client
+}
+
+func (BrasilApiProvider) Name() string { return "brasilAPI" }
+
+func (p BrasilApiProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).BrasilApiCep(ctx, cep)
+}
+
+func (BrasilApiProvider) Capabilities() Capabilities { return CapLookup | CapRemote }
+
+// ViaCepProvider exposes ViaCep as a CepProvider. A nil Client falls back
+// to DefaultClient.
+type ViaCepProvider struct {
+	Client *Client
+}
+
+func (ViaCepProvider) Name() string { return "ViaCEP" }
+
+func (p ViaCepProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).ViaCep(ctx, cep)
+}
+
+func (p ViaCepProvider) Search(ctx context.Context, q SearchQuery) ([]Address, error) {
+	return clientOrDefault(p.Client).ViaCepSearch(ctx, q)
+}
+
+func (ViaCepProvider) Capabilities() Capabilities { return CapLookup | CapRemote | CapSearch }
+
+// OpenCepProvider exposes OpenCep as a CepProvider. A nil Client falls back
+// to DefaultClient.
+type OpenCepProvider struct {
+	Client *Client
+}
+
+func (OpenCepProvider) Name() string { return "OpenCEP" }
+
+func (p OpenCepProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).OpenCep(ctx, cep)
+}
+
+func (OpenCepProvider) Capabilities() Capabilities { return CapLookup | CapRemote }
+
+// AwesomeApiProvider exposes AwesomeApiCep as a CepProvider. A nil Client
+// falls back to DefaultClient.
+type AwesomeApiProvider struct {
+	Client *Client
+}
+
+func (AwesomeApiProvider) Name() string { return "AwesomeAPI" }
+
+func (p AwesomeApiProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).AwesomeApiCep(ctx, cep)
+}
+
+func (AwesomeApiProvider) Capabilities() Capabilities { return CapLookup | CapRemote }
+
+// PostmonProvider exposes PostmonCep as a CepProvider. A nil Client falls
+// back to DefaultClient.
+type PostmonProvider struct {
+	Client *Client
+}
+
+func (PostmonProvider) Name() string { return "Postmon" }
+
+func (p PostmonProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).PostmonCep(ctx, cep)
+}
+
+func (PostmonProvider) Capabilities() Capabilities { return CapLookup | CapRemote }
+
+// ApiCepProvider exposes ApiCep as a CepProvider. A nil Client falls back to
+// DefaultClient.
+type ApiCepProvider struct {
+	Client *Client
+}
+
+func (ApiCepProvider) Name() string { return "ApiCEP" }
+
+func (p ApiCepProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return clientOrDefault(p.Client).ApiCep(ctx, cep)
+}
+
+func (ApiCepProvider) Capabilities() Capabilities { return CapLookup | CapRemote }
+
+// builtinProviders are the providers of this package, in their default order
+// of preference.
+var builtinProviders = []func(*Client) CepProvider{
+	func(c *Client) CepProvider { return BrasilApiProvider{Client: c} },
+	func(c *Client) CepProvider { return ViaCepProvider{Client: c} },
+	func(c *Client) CepProvider { return OpenCepProvider{Client: c} },
+	func(c *Client) CepProvider { return AwesomeApiProvider{Client: c} },
+	func(c *Client) CepProvider { return PostmonProvider{Client: c} },
+	func(c *Client) CepProvider { return ApiCepProvider{Client: c} },
+}
+
+// BuiltinProviderNames lists the names NewBuiltinProvider accepts.
+func BuiltinProviderNames() []string {
+	names := make([]string, len(builtinProviders))
+	for i, build := range builtinProviders {
+		names[i] = build(nil).Name()
+	}
+	return names
+}
+
+// NewBuiltinProvider builds the provider of this package called name, matched
+// case insensitively, backed by c.
+func NewBuiltinProvider(name string, c *Client) (CepProvider, error) {
+	for _, build := range builtinProviders {
+		if p := build(c); strings.EqualFold(p.Name(), strings.TrimSpace(name)) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cep provider %q, expected one of %s", name, strings.Join(BuiltinProviderNames(), ", "))
+}
@@ -0,0 +1,112 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+type fakeProvider struct {
+	name string
+}
+
+func (f fakeProvider) Name() string { return f.name }
+
+func (f fakeProvider) Lookup(ctx context.Context, cep string) (Address, error) {
+	return Address{Cep: cep, Source: f.name}, nil
+}
+
+func (f fakeProvider) Capabilities() Capabilities { return CapLookup }
+
+func providerNames(r *Registry) []string {
+	var names []string
+	for _, p := range r.Providers() {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRegistryRegisterRemoveReorder(t *testing.T) {
+	r := NewRegistry(fakeProvider{"a"}, fakeProvider{"b"})
+	r.Register(fakeProvider{"inHouse"})
+	if got, want := providerNames(r), []string{"a", "b", "inHouse"}; !equalNames(got, want) {
+		t.Fatalf("Register() got %v want %v", got, want)
+	}
+
+	// registering an existing name keeps its position
+	r.Register(fakeProvider{"a"})
+	if got, want := providerNames(r), []string{"a", "b", "inHouse"}; !equalNames(got, want) {
+		t.Errorf("Register() duplicate got %v want %v", got, want)
+	}
+
+	r.Reorder("inHouse", "unknown")
+	if got, want := providerNames(r), []string{"inHouse", "a", "b"}; !equalNames(got, want) {
+		t.Errorf("Reorder() got %v want %v", got, want)
+	}
+
+	if !r.Remove("a") {
+		t.Errorf("Remove() did not find provider a")
+	}
+	if r.Remove("a") {
+		t.Errorf("Remove() removed provider a twice")
+	}
+	if got, want := providerNames(r), []string{"inHouse", "b"}; !equalNames(got, want) {
+		t.Errorf("Remove() got %v want %v", got, want)
+	}
+
+	if _, ok := r.Get("b"); !ok {
+		t.Errorf("Get() did not find provider b")
+	}
+}
+
+func TestCapabilitiesHas(t *testing.T) {
+	c := ViaCepProvider{}.Capabilities()
+	if !c.Has(CapLookup | CapRemote) {
+		t.Errorf("ViaCepProvider capabilities %b missing lookup/remote", c)
+	}
+	if (fakeProvider{}).Capabilities().Has(CapRemote) {
+		t.Errorf("fakeProvider should not be remote")
+	}
+}
+
+func TestLocalCepProvider(t *testing.T) {
+	b := cepindex.NewBuilder()
+	b.Add(cepindex.Record{Cep: "20541155", State: "RJ", City: "Rio de Janeiro", Neighborhood: "Andaraí", Street: "Rua Paula Brito"})
+	var buf bytes.Buffer
+	b.WriteTo(&buf)
+	ix, err := cepindex.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+
+	p := LocalCepProvider{Index: ix}
+	if p.Capabilities().Has(CapRemote) {
+		t.Errorf("local provider claims to need the network")
+	}
+	address, err := p.Lookup(context.Background(), "20541155")
+	if err != nil || address.City != "Rio de Janeiro" || address.Source != "local" {
+		t.Errorf("Lookup() = %+v, %v", address, err)
+	}
+	if _, err := p.Lookup(context.Background(), "01001000"); !errors.Is(err, utils.ErrNotFound) {
+		t.Errorf("Lookup() of a CEP missing from the index returned %v", err)
+	}
+	if _, err := p.Lookup(context.Background(), "0100100"); !errors.Is(err, utils.ErrInvalidCep) {
+		t.Errorf("Lookup() of an invalid CEP returned %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// RateLimit allows Requests calls every Per on average, with bursts of up to
+// Burst calls. A zero Burst means Requests.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// ParseRateLimits parses a comma separated list of limits such as
+// "60/1m,1000000/720h". The unit may omit the 1, as in "60/m".
+func ParseRateLimits(v string) ([]RateLimit, error) {
+	var limits []RateLimit
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		requests, per, ok := strings.Cut(part, "/")
+		n, err := strconv.Atoi(requests)
+		if !ok || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q, expected requests/duration such as 60/1m", part)
+		}
+		if per != "" && (per[0] < '0' || per[0] > '9') {
+			per = "1" + per
+		}
+		d, err := time.ParseDuration(per)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q, expected requests/duration such as 60/1m", part)
+		}
+		limits = append(limits, RateLimit{Requests: n, Per: d})
+	}
+	return limits, nil
+}
+
+// DefaultRateLimitWait is how long a call may queue for a token unless
+// WithWeatherApiRateLimit says otherwise.
+const DefaultRateLimitWait = time.Second
+
+// RateLimitError is returned when a call would have to wait longer than
+// allowed for the rate limiter. It maps to 429 with a Retry-After.
+type RateLimitError struct {
+	Provider string
+	// Wait is how long until the limiter would let the call through.
+	Wait time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s outbound rate limit reached, retry in %s", e.Provider, e.Wait.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return utils.ErrRateLimited
+}
+
+func (e *RateLimitError) HTTPStatus() int {
+	return http.StatusTooManyRequests
+}
+
+func (e *RateLimitError) ProblemCode() string {
+	return "rate_limited"
+}
+
+func (e *RateLimitError) ProviderName() string {
+	return e.Provider
+}
+
+// RetryAfter implements utils.RetryAfterer.
+func (e *RateLimitError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+type tokenBucket struct {
+	capacity float64
+	// perSecond is the refill rate
+	perSecond float64
+	tokens    float64
+	last      time.Time
+}
+
+// waitFor returns how long until a token is available, refilling first.
+// tokens goes negative while calls are queued.
+func (b *tokenBucket) waitFor(now time.Time) time.Duration {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+}
+
+// RateLimiter is a set of token buckets, one per RateLimit; a call takes a
+// token from each of them. Calls queue up to maxWait for their tokens and
+// fail fast with a *RateLimitError beyond that. It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	provider string
+	buckets  []*tokenBucket
+	maxWait  time.Duration
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewRateLimiter builds a limiter that starts with full buckets.
+func NewRateLimiter(provider string, maxWait time.Duration, limits ...RateLimit) *RateLimiter {
+	l := &RateLimiter{provider: provider, maxWait: maxWait, now: time.Now}
+	now := l.now()
+	for _, limit := range limits {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = limit.Requests
+		}
+		l.buckets = append(l.buckets, &tokenBucket{
+			capacity:  float64(burst),
+			perSecond: float64(limit.Requests) / limit.Per.Seconds(),
+			tokens:    float64(burst),
+			last:      now,
+		})
+	}
+	return l
+}
+
+// Wait blocks until the call may go out. It returns a *RateLimitError right
+// away when that would take longer than the maximum wait or than what is
+// left before the deadline of ctx.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	var wait time.Duration
+	for _, b := range l.buckets {
+		if w := b.waitFor(now); w > wait {
+			wait = w
+		}
+	}
+	if wait > l.maxWait || !fitsDeadline(ctx, wait) {
+		l.mu.Unlock()
+		return &RateLimitError{Provider: l.provider, Wait: wait}
+	}
+	// reserve the tokens now so later callers queue behind this one
+	for _, b := range l.buckets {
+		b.tokens--
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// the call never goes out, give its tokens back
+		l.mu.Lock()
+		for _, b := range l.buckets {
+			b.tokens = math.Min(b.capacity, b.tokens+1)
+		}
+		l.mu.Unlock()
+		return classifyTransportError(ctx.Err())
+	}
+}
+
+// WithWeatherApiRateLimit throttles WeatherAPI calls, e.g. to stay within the
+// per minute and monthly quota of the plan. Calls queue up to maxWait for a
+// token, then fail with a *RateLimitError.
+func WithWeatherApiRateLimit(maxWait time.Duration, limits ...RateLimit) ClientOption {
+	return func(c *Client) error {
+		for _, limit := range limits {
+			if limit.Requests <= 0 || limit.Per <= 0 {
+				return fmt.Errorf("invalid rate limit %d/%s", limit.Requests, limit.Per)
+			}
+		}
+		if len(limits) == 0 {
+			c.weatherLimiter = nil
+			return nil
+		}
+		c.weatherLimiter = NewRateLimiter("weatherAPI", maxWait, limits...)
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("60/m, 1000000/720h")
+	if err != nil {
+		t.Fatalf("ParseRateLimits() returned an error: %v", err)
+	}
+	expected := []RateLimit{{Requests: 60, Per: time.Minute}, {Requests: 1000000, Per: 720 * time.Hour}}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("ParseRateLimits() = %+v", limits)
+	}
+	for _, v := range []string{"60", "x/m", "60/soon", "0/m", "60/0s"} {
+		if _, err := ParseRateLimits(v); err == nil {
+			t.Errorf("ParseRateLimits(%q) accepted an invalid limit", v)
+		}
+	}
+}
+
+func TestRateLimiterQueuesThenFailsFast(t *testing.T) {
+	l := NewRateLimiter("test", 80*time.Millisecond, RateLimit{Requests: 1, Per: 50 * time.Millisecond})
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() with a full bucket returned %v", err)
+	}
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() within the maximum wait returned %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("second call went out after %v, expected it to queue", elapsed)
+	}
+
+	// with another call queued the next token is 100ms away
+	go l.Wait(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	err := l.Wait(context.Background())
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, utils.ErrRateLimited) || limitErr.RetryAfter() <= 0 {
+		t.Fatalf("Wait() beyond the maximum wait returned %v", err)
+	}
+
+	// nor past the deadline of the caller
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, utils.ErrRateLimited) {
+		t.Errorf("Wait() past the deadline returned %v", err)
+	}
+}
+
+func TestWeatherApiRateLimit(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"current":{"temp_c":21.5}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithWeatherApiURL(srv.URL), WithWeatherApiKeys("key"),
+		WithWeatherApiRateLimit(0, RateLimit{Requests: 1, Per: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	if _, err := c.CurrentWeather(context.Background(), "London", "en"); err != nil {
+		t.Fatalf("CurrentWeather() returned an error: %v", err)
+	}
+	_, err = c.CurrentWeather(context.Background(), "London", "en")
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) || limitErr.HTTPStatus() != http.StatusTooManyRequests {
+		t.Errorf("CurrentWeather() over the limit returned %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, expected 1", calls)
+	}
+
+	// invalid limits are refused
+	if _, err := NewClient(WithWeatherApiRateLimit(time.Second, RateLimit{Requests: 0, Per: time.Hour})); err == nil {
+		t.Errorf("NewClient() accepted a limit of zero requests")
+	}
+}
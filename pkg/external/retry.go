@@ -0,0 +1,135 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// RetryPolicy decides how Client retries transient upstream failures: network
+// errors, timeouts of a single attempt and 408, 429, 500, 502, 503 and 504
+// answers. Definitive answers such as 404 are never retried, and neither are
+// requests that are not safe to replay.
+type RetryPolicy struct {
+	// MaxAttempts counts the first call too. One or less disables retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles on every
+	// further attempt, up to MaxDelay, and is fully jittered.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After asking for a longer wait is
+	// not honoured and the answer is returned as is.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy says otherwise.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// WithRetryPolicy sets how outbound calls are retried.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) error {
+		c.retry = p
+		return nil
+	}
+}
+
+// backoff returns the wait before attempt+1, honouring a Retry-After header
+// of resp. ok is false when the server asks for more than MaxDelay.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) (d time.Duration, ok bool) {
+	if resp != nil {
+		if after, found := retryAfter(resp.Header.Get("Retry-After"), time.Now()); found {
+			return after, after <= p.MaxDelay
+		}
+	}
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0, true
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1)), true
+}
+
+// retryAfter parses a Retry-After value, either seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryable reports whether the outcome of an attempt is worth another one.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) &&
+			(errors.Is(err, utils.ErrTimeout) || errors.Is(err, utils.ErrUpstreamUnavailable))
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusTooManyRequests:
+		// without Retry-After the limit is unlikely to clear within a backoff
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// replayable reports whether req may be sent again: the method must be
+// idempotent, or the caller must have set an Idempotency-Key, and a body must
+// be rewindable.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of req with a fresh body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// fitsDeadline reports whether waiting d still leaves the attempt some time
+// before the deadline of ctx.
+func fitsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Now().Add(d).Before(deadline)
+}
+
+// discard drains and closes the body of a response that is not returned, so
+// its connection can be reused.
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,162 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+var fastRetries = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"cep":"20541-155","uf":"RJ","localidade":"Rio de Janeiro"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithViaCepURL(srv.URL), WithRetryPolicy(fastRetries))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	result, err := c.ViaCep(context.Background(), "20541155")
+	if err != nil || result.City != "Rio de Janeiro" {
+		t.Fatalf("ViaCep() = %+v, %v", result, err)
+	}
+	if calls != 3 {
+		t.Errorf("server called %d times, expected 3", calls)
+	}
+
+	// the last failure is returned once the attempts run out
+	atomic.StoreInt32(&calls, -10)
+	_, err = c.ViaCep(context.Background(), "20541155")
+	if !errors.Is(err, utils.ErrUpstreamUnavailable) || calls != -7 {
+		t.Errorf("ViaCep() returned %v after %d calls", err, calls+10)
+	}
+}
+
+func TestClientDoesNotRetryDefinitiveAnswers(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch {
+		case strings.Contains(r.URL.Path, "99999999"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			// rate limited without saying for how long
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithBrasilApiURL(srv.URL), WithRetryPolicy(fastRetries))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	for _, cep := range []string{"99999999", "20541155", "123"} {
+		atomic.StoreInt32(&calls, 0)
+		c.BrasilApiCep(context.Background(), cep)
+		if calls > 1 {
+			t.Errorf("BrasilApiCep(%s) called the server %d times", cep, calls)
+		}
+	}
+
+	// not idempotent
+	atomic.StoreInt32(&calls, 0)
+	srvErr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srvErr.Close()
+	req, _ := http.NewRequest(http.MethodPost, srvErr.URL, strings.NewReader("{}"))
+	resp, err := c.do(req)
+	if err != nil {
+		t.Fatalf("do() returned an error: %v", err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Errorf("POST without an Idempotency-Key was sent %d times", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	req, _ = http.NewRequest(http.MethodPost, srvErr.URL, strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "abc")
+	resp, _ = c.do(req)
+	resp.Body.Close()
+	if calls != 3 {
+		t.Errorf("POST with an Idempotency-Key was sent %d times, expected 3", calls)
+	}
+}
+
+func TestClientRetryHonoursRetryAfterAndDeadline(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	send := func(ctx context.Context, after string) time.Duration {
+		atomic.StoreInt32(&calls, 0)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?after="+after, nil)
+		start := time.Now()
+		resp, err := c.do(req)
+		if err != nil {
+			t.Fatalf("do() returned an error: %v", err)
+		}
+		resp.Body.Close()
+		return time.Since(start)
+	}
+
+	if elapsed := send(context.Background(), "1"); calls != 2 || elapsed < time.Second {
+		t.Errorf("Retry-After of 1s: %d calls in %v", calls, elapsed)
+	}
+	// longer than MaxDelay
+	if send(context.Background(), "60"); calls != 1 {
+		t.Errorf("Retry-After above the maximum delay was retried, %d calls", calls)
+	}
+	// longer than what is left of the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if elapsed := send(ctx, "1"); calls != 1 || elapsed > 500*time.Millisecond {
+		t.Errorf("retry past the deadline: %d calls in %v", calls, elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+		found bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"Fri, 01 Mar 2024 12:00:05 GMT", 5 * time.Second, true},
+		{"Fri, 01 Mar 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		got, found := retryAfter(tc.value, now)
+		if got != tc.want || found != tc.found {
+			t.Errorf("retryAfter(%q) = %v, %v, expected %v, %v", tc.value, got, found, tc.want, tc.found)
+		}
+	}
+}
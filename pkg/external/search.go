@@ -0,0 +1,72 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// SearchQuery finds the CEPs of a street. Every field is required.
+type SearchQuery struct {
+	State  string `json:"uf"`
+	City   string `json:"city"`
+	Street string `json:"street"`
+}
+
+// minSearchLength is the shortest city or street ViaCEP searches for.
+const minSearchLength = 3
+
+// Validate checks q the way the upstream search does, so a bad query never
+// leaves the process.
+func (q SearchQuery) Validate() error {
+	if len(strings.TrimSpace(q.State)) != 2 {
+		return utils.InvalidSearchError.WithMessage("uf must have 2 letters")
+	}
+	if len([]rune(strings.TrimSpace(q.City))) < minSearchLength {
+		return utils.InvalidSearchError.WithMessage("city must have at least 3 characters")
+	}
+	if len([]rune(strings.TrimSpace(q.Street))) < minSearchLength {
+		return utils.InvalidSearchError.WithMessage("street must have at least 3 characters")
+	}
+	return nil
+}
+
+// AddressSearcher is implemented by providers with CapSearch, which can list
+// the addresses of a street.
+type AddressSearcher interface {
+	Search(ctx context.Context, q SearchQuery) ([]Address, error)
+}
+
+// ViaCepSearch lists the addresses matching q on ViaCEP using DefaultClient.
+func ViaCepSearch(ctx context.Context, q SearchQuery) ([]Address, error) {
+	return DefaultClient().ViaCepSearch(ctx, q)
+}
+
+// ViaCepSearch lists the addresses matching q through the
+// /ws/{UF}/{city}/{street}/json/ form of ViaCEP, which returns at most 50 of
+// them. No match is an empty list, not an error.
+func (c *Client) ViaCepSearch(ctx context.Context, q SearchQuery) ([]Address, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	u := c.viaCepUrl + url.PathEscape(strings.ToUpper(strings.TrimSpace(q.State))) + "/" +
+		url.PathEscape(strings.TrimSpace(q.City)) + "/" + url.PathEscape(strings.TrimSpace(q.Street)) + "/json/"
+
+	var data []AddressDataViaCep
+	if err := c.getCep(ctx, ViaCepProvider{}.Name(), u, &data); err != nil {
+		var refused utils.HttpError
+		if errors.As(err, &refused) && errors.Is(err, utils.ErrInvalidCep) {
+			return nil, utils.InvalidSearchError.WithMessage(refused.Message)
+		}
+		return nil, err
+	}
+
+	addresses := make([]Address, 0, len(data))
+	for _, d := range data {
+		addresses = append(addresses, d.address())
+	}
+	return addresses, nil
+}
@@ -3,43 +3,79 @@ package external
 import (
 	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/JonecoBoy/tempByCep/pkg/utils"
 )
 
+const viaCepBaseUrl = "http://viacep.com.br/ws/"
+
 type AddressDataViaCep struct {
 	Cep          string `json:"cep"`
 	State        string `json:"uf"`
 	City         string `json:"localidade"`
 	Neighborhood string `json:"bairro"`
 	Street       string `json:"logradouro"`
+	Complement   string `json:"complemento"`
+	Ibge         string `json:"ibge"`
+	Ddd          string `json:"ddd"`
+	Gia          string `json:"gia"`
+	Siafi        string `json:"siafi"`
+	// StateName and Region are only sent by the newer ViaCEP
+	StateName string `json:"estado"`
+	Region    string `json:"regiao"`
+}
+
+// address maps the answer of ViaCEP to an Address.
+func (d AddressDataViaCep) address() Address {
+	return Address{
+		Cep:          d.Cep,
+		State:        d.State,
+		City:         d.City,
+		Neighborhood: d.Neighborhood,
+		Street:       d.Street,
+		Source:       "ViaCEP",
+		Complement:   d.Complement,
+		Ibge:         d.Ibge,
+		Ddd:          d.Ddd,
+		Gia:          d.Gia,
+		Siafi:        d.Siafi,
+		StateName:    d.StateName,
+		Region:       d.Region,
+	}
+}
+
+// ViaCep looks cep up on ViaCEP using DefaultClient.
+func ViaCep(ctx context.Context, cep string) (Address, error) {
+	return DefaultClient().ViaCep(ctx, cep)
 }
 
-func ViaCep(cep string) (Address, error) {
+func (c *Client) ViaCep(ctx context.Context, cep string) (Address, error) {
 	err := utils.ValidateCep(cep)
 	if err != nil {
 		return Address{}, utils.InvalidZipError
 	}
-	ctx := context.Background()
 	// o contexto expira em 1 segundo!
 	ctx, cancel := context.WithTimeout(ctx, requestExpirationTime)
 	defer cancel() // de alguma forma nosso contexto será cancelado
-	req, err := http.NewRequestWithContext(ctx, "GET", "http://viacep.com.br/ws/"+cep+"/json/", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.viaCepUrl+cep+"/json/", nil)
 
 	if err != nil {
 		return Address{}, err
 	}
 
 	// faz a request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.do(req)
 
 	if err != nil {
 		return Address{}, err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return Address{}, utils.UpstreamError.WithMessage("viacep returned status " + resp.Status)
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		return Address{}, utils.ZipNotFoundError
@@ -47,12 +83,10 @@ func ViaCep(cep string) (Address, error) {
 	}
 
 	if ctx.Err() == context.DeadlineExceeded {
-		fmt.Println("Api fetch timeout exceeed.")
-		return Address{}, errors.New("api fetch timeout exceeed")
+		return Address{}, utils.TimeoutError.WithMessage("api fetch timeout exceeded")
 	}
 
 	// depois de tudo termina e faz o body
-	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return Address{}, err
@@ -68,15 +102,6 @@ func ViaCep(cep string) (Address, error) {
 		return Address{}, utils.ZipNotFoundError
 	}
 
-	addressData := Address{
-		Cep:          jsonData.Cep,
-		State:        jsonData.State,
-		City:         jsonData.City,
-		Neighborhood: jsonData.Neighborhood,
-		Street:       jsonData.Street,
-		Source:       "ViaCEP",
-	}
-
-	return addressData, nil
+	return jsonData.address(), nil
 
 }
@@ -1,6 +1,7 @@
 package external
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +13,9 @@ import (
 	"time"
 )
 
-type searchReturn struct {
+// WeatherLocation is a place known to WeatherAPI, as its search returns it.
+// Region is the state for Brazilian places, without accents.
+type WeatherLocation struct {
 	Id      int32   `json:"id"`
 	Name    string  `json:"name"`
 	Region  string  `json:"region"`
@@ -204,56 +207,88 @@ type TimeZone struct {
 	Location *Location `json:"location"`
 }
 
-const apiKey = "cf7ea6d61fd247d78e9171401240206"
-const baseUrl = "https://api.weatherapi.com/v1"
+const weatherApiBaseUrl = "https://api.weatherapi.com/v1/"
 
 const weatherRequestExpirationTime = 60 * time.Second
 
-func doRequest(method string, path string, params map[string]string) (*http.Response, error) {
+// cancelOnClose releases the request context once the caller is done with the body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doRequest calls WeatherAPI with the current key of the ring. When the key is
+// refused and there are other keys left, the request is repeated with the next.
+func (c *Client) doRequest(ctx context.Context, method string, path string, params map[string]string) (*http.Response, error) {
+	// the configuration error says more than the missing key it causes
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
 	path = strings.ReplaceAll(path, "/", "")
 	method = strings.ToUpper(method)
-	//ctx := context.Background()
-	//ctx, cancel := context.WithTimeout(ctx, weatherRequestExpirationTime)
-	//defer cancel() // de alguma forma nosso contexto será cancelado
 
-	u, err := url.Parse(baseUrl + "/" + path)
+	u, err := url.Parse(c.weatherApiUrl + path)
 	if err != nil {
 		return nil, err
 	}
 
-	// add api key
-	params["key"] = apiKey
-
-	//parseando e adicionando do map
-	q := u.Query()
-	for k, v := range params {
-		q.Set(k, v)
+	keys := c.weatherKeys.Len()
+	if keys == 0 {
+		return nil, ErrNoWeatherApiKey
 	}
-	u.RawQuery = q.Encode()
 
-	//req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
-	req, err := http.NewRequest(method, u.String(), nil)
+	ctx, cancel := context.WithTimeout(ctx, weatherRequestExpirationTime)
+	for attempt := 1; ; attempt++ {
+		// add api key
+		key := c.weatherKeys.Current()
+		params["key"] = key
 
-	if err != nil {
-		return nil, err
-	}
+		//parseando e adicionando do map
+		q := u.Query()
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
 
-	// faz a request
-	resp, err := http.DefaultClient.Do(req)
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 
-	if err != nil {
-		return nil, err
-	}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		// faz a request
+		resp, err := c.doLimited(req, c.weatherLimiter)
 
-	//if ctx.Err() == context.DeadlineExceeded {
-	//	fmt.Println("Api fetch timeout exceeed.")
-	//	return nil, errors.New("Api fetch timeout exceeed.")
-	//}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
 
-	return resp, nil
+		if shouldRotateKey(resp.StatusCode) && attempt < keys {
+			resp.Body.Close()
+			c.weatherKeys.Rotate(key)
+			continue
+		}
+
+		// the context is cancelled when the caller closes the body
+		resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+}
+
+// CurrentWeather fetches the current weather for query using DefaultClient.
+func CurrentWeather(ctx context.Context, query string, lang string) (CurrentModel, error) {
+	return DefaultClient().CurrentWeather(ctx, query, lang)
 }
 
-func CurrentWeather(query string, lang string) (CurrentModel, error) {
+func (c *Client) CurrentWeather(ctx context.Context, query string, lang string) (CurrentModel, error) {
 	// Define the parameters for the request
 	params := map[string]string{
 		"q":    query,
@@ -261,10 +296,14 @@ func CurrentWeather(query string, lang string) (CurrentModel, error) {
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "current.json", params)
+	resp, err := c.doRequest(ctx, "GET", "current.json", params)
 	if err != nil {
 		return CurrentModel{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return CurrentModel{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -278,12 +317,15 @@ func CurrentWeather(query string, lang string) (CurrentModel, error) {
 	if err != nil {
 		return CurrentModel{}, fmt.Errorf("unmarshalling response body: %v", err)
 	}
+	if current.Current == nil {
+		return CurrentModel{}, &WeatherApiError{Status: resp.StatusCode, Message: "response without current weather"}
+	}
 
 	// Return the current weather data
 	return current, nil
 }
 
-func forecast(query string, lang string, days int) (Forecast, error) {
+func (c *Client) forecast(ctx context.Context, query string, lang string, days int) (Forecast, error) {
 	// Define the parameters for the request
 	params := map[string]string{
 		"q":    query,
@@ -292,10 +334,14 @@ func forecast(query string, lang string, days int) (Forecast, error) {
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "forecast.json", params)
+	resp, err := c.doRequest(ctx, "GET", "forecast.json", params)
 	if err != nil {
 		return Forecast{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return Forecast{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -314,7 +360,7 @@ func forecast(query string, lang string, days int) (Forecast, error) {
 	return forecast, nil
 }
 
-func ip(ipaddress string) (IP, error) {
+func (c *Client) ip(ctx context.Context, ipaddress string) (IP, error) {
 	// Remove all dots from the IP address
 	ipaddress = strings.ReplaceAll(ipaddress, ".", "")
 
@@ -330,10 +376,14 @@ func ip(ipaddress string) (IP, error) {
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "ip.json", params)
+	resp, err := c.doRequest(ctx, "GET", "ip.json", params)
 	if err != nil {
 		return IP{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return IP{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -352,31 +402,51 @@ func ip(ipaddress string) (IP, error) {
 	return ip, nil
 }
 
-func search(toSearch string) (searchReturn, error) {
+func (c *Client) search(ctx context.Context, toSearch string) (WeatherLocation, error) {
+	results, err := c.SearchLocations(ctx, toSearch)
+	if err != nil {
+		return WeatherLocation{}, err
+	}
+
+	if len(results) == 0 {
+		return WeatherLocation{}, fmt.Errorf("no results returned")
+	}
+
+	return results[0], nil
+}
+
+// SearchLocations searches WeatherAPI places using DefaultClient.
+func SearchLocations(ctx context.Context, toSearch string) ([]WeatherLocation, error) {
+	return DefaultClient().SearchLocations(ctx, toSearch)
+}
+
+// SearchLocations lists the places WeatherAPI matches toSearch with, best
+// match first. No match is an empty list.
+func (c *Client) SearchLocations(ctx context.Context, toSearch string) ([]WeatherLocation, error) {
 	param := map[string]string{"q": toSearch}
-	resp, err := doRequest("GET", "search.json", param)
+	resp, err := c.doRequest(ctx, "GET", "search.json", param)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return nil, err
 	}
 	body, err := io.ReadAll(resp.Body)
 
 	if err != nil {
-		return searchReturn{}, fmt.Errorf("reading response body: %v", err)
+		return nil, fmt.Errorf("reading response body: %v", err)
 	}
-	var results []searchReturn
+	var results []WeatherLocation
 	err = json.Unmarshal(body, &results)
 	if err != nil {
-		return searchReturn{}, fmt.Errorf("unmarshalling response body: %v", err)
+		return nil, fmt.Errorf("unmarshalling response body: %v", err)
 	}
 
-	if len(results) == 0 {
-		return searchReturn{}, fmt.Errorf("no results returned")
-	}
-
-	return results[0], nil
+	return results, nil
 }
 
-func future(query string, lang string, date string) (Forecast, error) {
+func (c *Client) future(ctx context.Context, query string, lang string, date string) (Forecast, error) {
 	// Parse the date string into a time.Time value
 	dt, err := time.Parse("2006-01-02", date)
 	if err != nil {
@@ -402,10 +472,14 @@ func future(query string, lang string, date string) (Forecast, error) {
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "forecast.json", params)
+	resp, err := c.doRequest(ctx, "GET", "forecast.json", params)
 	if err != nil {
 		return Forecast{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return Forecast{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -424,14 +498,18 @@ func future(query string, lang string, date string) (Forecast, error) {
 	return forecast, nil
 }
 
-func timezone(query string) (TimeZone, error) {
+func (c *Client) timezone(ctx context.Context, query string) (TimeZone, error) {
 	params := map[string]string{
 		"q": query,
 	}
-	response, err := doRequest("GET", "timezone.json", params)
+	response, err := c.doRequest(ctx, "GET", "timezone.json", params)
 	if err != nil {
 		return TimeZone{}, err
 	}
+	defer response.Body.Close()
+	if err := checkWeatherResponse(response); err != nil {
+		return TimeZone{}, err
+	}
 
 	dataJson, err := io.ReadAll(response.Body)
 	if err != nil {
@@ -447,7 +525,7 @@ func timezone(query string) (TimeZone, error) {
 	return location, nil
 }
 
-func astronomy(query string, date string) (Astronomy, error) {
+func (c *Client) astronomy(ctx context.Context, query string, date string) (Astronomy, error) {
 	// Define the parameters for the request
 	params := map[string]string{
 		"q":  query,
@@ -455,10 +533,14 @@ func astronomy(query string, date string) (Astronomy, error) {
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "astronomy.json", params)
+	resp, err := c.doRequest(ctx, "GET", "astronomy.json", params)
 	if err != nil {
 		return Astronomy{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return Astronomy{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -477,7 +559,7 @@ func astronomy(query string, date string) (Astronomy, error) {
 	return astronomy, nil
 }
 
-func marine(query string, lang string, days int, date string, unixdt int, hour int) (Marine, error) {
+func (c *Client) marine(ctx context.Context, query string, lang string, days int, date string, unixdt int, hour int) (Marine, error) {
 	params := map[string]string{
 		"q":    query,
 		"days": strconv.Itoa(days),
@@ -498,10 +580,14 @@ func marine(query string, lang string, days int, date string, unixdt int, hour i
 	}
 
 	// Make the request
-	resp, err := doRequest("GET", "marine.json", params)
+	resp, err := c.doRequest(ctx, "GET", "marine.json", params)
 	if err != nil {
 		return Marine{}, err
 	}
+	defer resp.Body.Close()
+	if err := checkWeatherResponse(resp); err != nil {
+		return Marine{}, err
+	}
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
@@ -0,0 +1,107 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// WeatherAPI error codes, see https://www.weatherapi.com/docs/#intro-error-codes
+const (
+	WeatherCodeKeyNotProvided  = 1002
+	WeatherCodeQueryMissing    = 1003
+	WeatherCodeInvalidUrl      = 1005
+	WeatherCodeNoLocation      = 1006
+	WeatherCodeInvalidKey      = 2006
+	WeatherCodeQuotaExceeded   = 2007
+	WeatherCodeKeyDisabled     = 2008
+	WeatherCodeNoAccess        = 2009
+	WeatherCodeInternalFailure = 9999
+)
+
+// WeatherApiError is an error reply from weatherapi.com, e.g.
+// {"error":{"code":1006,"message":"No matching location found."}}.
+type WeatherApiError struct {
+	// Status is the HTTP status code of the reply.
+	Status  int    `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *WeatherApiError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("weatherapi returned status %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("weatherapi error %d: %s", e.Code, e.Message)
+}
+
+// HTTPStatus maps the upstream error to the status this service should answer
+// with: 404 for an unknown location, 503 when the quota is exhausted and 502
+// for any other upstream failure.
+func (e *WeatherApiError) HTTPStatus() int {
+	switch e.Code {
+	case WeatherCodeNoLocation:
+		return http.StatusNotFound
+	case WeatherCodeQuotaExceeded:
+		return http.StatusServiceUnavailable
+	}
+	if e.Status == http.StatusTooManyRequests {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// ProblemCode returns a stable identifier for the failure.
+func (e *WeatherApiError) ProblemCode() string {
+	switch e.HTTPStatus() {
+	case http.StatusNotFound:
+		return "location_not_found"
+	case http.StatusServiceUnavailable:
+		return "weather_quota_exceeded"
+	}
+	return "weather_upstream_error"
+}
+
+// Unwrap exposes the error kind, e.g. errors.Is(err, utils.ErrRateLimited)
+// when the quota is exhausted.
+func (e *WeatherApiError) Unwrap() error {
+	switch e.HTTPStatus() {
+	case http.StatusNotFound:
+		return utils.ErrNotFound
+	case http.StatusServiceUnavailable:
+		return utils.ErrRateLimited
+	}
+	return utils.ErrUpstreamUnavailable
+}
+
+// ProviderName names WeatherAPI as the failing provider.
+func (e *WeatherApiError) ProviderName() string {
+	return "weatherAPI"
+}
+
+type weatherErrorBody struct {
+	Error *WeatherApiError `json:"error"`
+}
+
+// checkWeatherResponse turns a non 200 WeatherAPI reply into a *WeatherApiError,
+// decoding the provider code when the body carries one.
+func checkWeatherResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	werr := &WeatherApiError{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return werr
+	}
+	var decoded weatherErrorBody
+	if json.Unmarshal(body, &decoded) == nil && decoded.Error != nil {
+		decoded.Error.Status = resp.StatusCode
+		return decoded.Error
+	}
+	return werr
+}
@@ -1,19 +1,32 @@
 package external
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 )
 
+// the WeatherAPI key is not committed, these tests need it in the environment
+func requireWeatherApiKey(t *testing.T) {
+	t.Helper()
+	if !DefaultClient().HasWeatherApiKey() {
+		t.Skipf("set %s to run the WeatherAPI tests", EnvWeatherApiKey)
+	}
+}
+
 func TestDoRequest(t *testing.T) {
+	requireWeatherApiKey(t)
 	method := "GET"
 	path := "current.json"
 	params := map[string]string{
 		"q": "London",
 	}
 
-	resp, err := doRequest(method, path, params)
+	resp, err := DefaultClient().doRequest(context.Background(), method, path, params)
 	if err != nil {
 		t.Errorf("doRequest() returned an error: %v", err)
 	}
@@ -24,8 +37,9 @@ func TestDoRequest(t *testing.T) {
 }
 
 func TestSearch(t *testing.T) {
+	requireWeatherApiKey(t)
 	query := "mage-rio de janeiro-brazil"
-	expected := searchReturn{
+	expected := WeatherLocation{
 
 		Id:      279745,
 		Name:    "Mage",
@@ -36,7 +50,7 @@ func TestSearch(t *testing.T) {
 		Url:     "mage-rio-de-janeiro-brazil",
 	}
 
-	result, err := search(query)
+	result, err := DefaultClient().search(context.Background(), query)
 	if err != nil {
 		t.Errorf("search() returned an error: %v", err)
 	}
@@ -47,10 +61,11 @@ func TestSearch(t *testing.T) {
 }
 
 func TestCurrent(t *testing.T) {
+	requireWeatherApiKey(t)
 	query := "mage-rio de janeiro-brazil"
 	lang := "pt"
 
-	result, err := CurrentWeather(query, lang)
+	result, err := CurrentWeather(context.Background(), query, lang)
 	if err != nil {
 		t.Errorf("Current() returned an error: %v", err)
 	}
@@ -80,11 +95,12 @@ func TestCurrent(t *testing.T) {
 }
 
 func TestForecast(t *testing.T) {
+	requireWeatherApiKey(t)
 	query := "mage-rio de janeiro-brazil"
 	lang := "pt"
 	days := 3
 
-	result, err := forecast(query, lang, days)
+	result, err := DefaultClient().forecast(context.Background(), query, lang, days)
 	if err != nil {
 		t.Errorf("forecast() returned an error: %v", err)
 	}
@@ -110,9 +126,10 @@ func TestForecast(t *testing.T) {
 	}
 }
 func TestIP(t *testing.T) {
+	requireWeatherApiKey(t)
 	ipaddress := "192.168.1.1"
 
-	result, err := ip(ipaddress)
+	result, err := DefaultClient().ip(context.Background(), ipaddress)
 	if err != nil {
 		t.Errorf("ip() returned an error: %v", err)
 	}
@@ -136,12 +153,13 @@ func TestIP(t *testing.T) {
 	}
 }
 func TestFuture(t *testing.T) {
+	requireWeatherApiKey(t)
 	code := "mage-rio de janeiro-brazil"
 	lang := "pt"
 
 	date := time.Now().Format("2006-01-02") // error test
 
-	_, err := future(code, lang, date)
+	_, err := DefaultClient().future(context.Background(), code, lang, date)
 	if err == nil {
 		t.Errorf("future() did not return an error for an invalid date")
 	}
@@ -149,7 +167,7 @@ func TestFuture(t *testing.T) {
 	//valid test
 	date = time.Now().AddDate(0, 0, 20).Format("2006-01-02")
 
-	result, err := future(code, lang, date)
+	result, err := DefaultClient().future(context.Background(), code, lang, date)
 	if err != nil {
 		t.Errorf("future() returned an error: %v", err)
 	}
@@ -178,9 +196,10 @@ func TestFuture(t *testing.T) {
 }
 
 func TestTimezone(t *testing.T) {
+	requireWeatherApiKey(t)
 	code := "mage-rio de janeiro-brazil"
 
-	result, err := timezone(code)
+	result, err := DefaultClient().timezone(context.Background(), code)
 	if err != nil {
 		t.Errorf("timezone() returned an error: %v", err)
 	}
@@ -191,10 +210,11 @@ func TestTimezone(t *testing.T) {
 }
 
 func TestAstronomy(t *testing.T) {
+	requireWeatherApiKey(t)
 	query := "mage-rio de janeiro-brazil"
 	date := "2024-01-01" // This date should be on or after 1st Jan, 2015
 
-	result, err := astronomy(query, date)
+	result, err := DefaultClient().astronomy(context.Background(), query, date)
 	if err != nil {
 		t.Errorf("astronomy() returned an error: %v", err)
 	}
@@ -223,10 +243,11 @@ func TestAstronomy(t *testing.T) {
 }
 
 func TestMarine(t *testing.T) {
+	requireWeatherApiKey(t)
 	query := "mage - rio de janeiro - brazil"
 	days := 3
 
-	result, err := marine(query, "pt", days, "", 0, 0)
+	result, err := DefaultClient().marine(context.Background(), query, "pt", days, "", 0, 0)
 	if err != nil {
 		t.Errorf("marine() returned an error: %v", err)
 	}
@@ -256,3 +277,44 @@ func TestMarine(t *testing.T) {
 		}
 	}
 }
+
+func TestCurrentWeatherErrorPayload(t *testing.T) {
+	cases := []struct {
+		status     int
+		body       string
+		code       int
+		httpStatus int
+	}{
+		{http.StatusBadRequest, `{"error":{"code":1006,"message":"No matching location found."}}`, WeatherCodeNoLocation, http.StatusNotFound},
+		{http.StatusForbidden, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, WeatherCodeQuotaExceeded, http.StatusServiceUnavailable},
+		{http.StatusInternalServerError, `upstream exploded`, 0, http.StatusBadGateway},
+		// 200 without a current block must not reach the handler as a nil pointer
+		{http.StatusOK, `{}`, 0, http.StatusBadGateway},
+	}
+
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+		c, err := NewClient(WithWeatherApiURL(srv.URL), WithWeatherApiKeys("key"))
+		if err != nil {
+			t.Fatalf("NewClient() returned an error: %v", err)
+		}
+
+		_, err = c.CurrentWeather(context.Background(), "nowhere", "pt")
+		srv.Close()
+
+		var werr *WeatherApiError
+		if !errors.As(err, &werr) {
+			t.Errorf("CurrentWeather() returned %v, expected a *WeatherApiError", err)
+			continue
+		}
+		if werr.Code != tc.code {
+			t.Errorf("WeatherApiError.Code = %d, expected %d", werr.Code, tc.code)
+		}
+		if werr.HTTPStatus() != tc.httpStatus {
+			t.Errorf("WeatherApiError.HTTPStatus() = %d, expected %d", werr.HTTPStatus(), tc.httpStatus)
+		}
+	}
+}
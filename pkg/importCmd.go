@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+)
+
+const importUsage = `usage: tempByCep import-cep -o index [-delim c] [-columns names] [-latin1] file.csv...
+
+Builds the local CEP index read by the local provider (see CEP_INDEX_FILE)
+from CEP dumps. Files need a cep column and may have uf, cidade, bairro and
+logradouro columns (or state, city, neighborhood, street). Later files win
+over earlier ones for the same CEP.
+
+flags:
+  -o path          index file to write
+  -delim c         field delimiter, detected from the first line by default
+  -columns names   comma separated column names of files without a header
+  -latin1          the files are ISO-8859-1, as the Correios DNE
+`
+
+// runImportCommand implements the "import-cep" sub command and returns the
+// exit code.
+func runImportCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import-cep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, importUsage) }
+	out := fs.String("o", "", "index file to write")
+	delim := fs.String("delim", "", "field delimiter")
+	columns := fs.String("columns", "", "column names of files without a header")
+	latin1 := fs.Bool("latin1", false, "decode the files as ISO-8859-1")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *out == "" || utf8.RuneCountInString(*delim) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	opts := cepindex.CSVOptions{Latin1: *latin1}
+	if *delim != "" {
+		opts.Comma, _ = utf8.DecodeRuneInString(*delim)
+	}
+	if *columns != "" {
+		opts.Columns = strings.Split(*columns, ",")
+	}
+
+	builder := cepindex.NewBuilder()
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "opening %s: %v\n", path, err)
+			return 1
+		}
+		added, skipped, err := cepindex.ReadCSV(f, opts, builder.Add)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "reading %s: %v\n", path, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: %d rows imported, %d skipped\n", path, added, skipped)
+	}
+
+	if err := builder.WriteFile(*out); err != nil {
+		fmt.Fprintf(stderr, "writing %s: %v\n", *out, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d ceps written to %s\n", builder.Len(), *out)
+	return 0
+}
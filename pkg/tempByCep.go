@@ -1,153 +1,52 @@
 package main
 
 import (
-	"crypto/tls"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"net/http"
-	"strings"
-	"time"
+	"os"
 
+	"github.com/JonecoBoy/tempByCep/pkg/cache"
 	"github.com/JonecoBoy/tempByCep/pkg/external"
-	"github.com/JonecoBoy/tempByCep/pkg/utils"
+	"github.com/JonecoBoy/tempByCep/pkg/tempbycep"
 )
 
-type Result struct {
-	Address external.Address
-	Err     error
-}
-
-type tempResponse struct {
-	// Location *external.Location `json:"location"`
-	Temp_C float32 `json:"temp_c"`
-	Temp_F float32 `json:"temp_f"`
-	Temp_K float32 `json:"temp_k"`
-}
-
 func main() {
-	// allow insecure connection
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	mux := http.NewServeMux()
-	// podia ter passado anonima
-	mux.HandleFunc("/cep/", cepHandler)
-	mux.HandleFunc("/temp/", tempHandler)
-	mux.HandleFunc("/", HomeHandler)
-
-	log.Print("Listening...")
-	http.ListenAndServe(":8080", mux)
-
-}
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte("pong"))
-}
-
-func cepHandler(w http.ResponseWriter, r *http.Request) {
-	path := strings.Split(r.URL.Path, "/")
-	if len(path) < 3 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	}
-	cep := path[2]
-	// remove separator if exists
-	cep = strings.ReplaceAll(cep, "-", "")
-	c, err := CepConcurrency(cep)
-	if err != nil {
-		fmt.Println(err.Error())
-		w.Write([]byte(err.Error()))
-		return
-	}
-	// Set the Content-Type header to application/json
-	w.Header().Set("Content-Type", "application/json")
-
-	jsonData, err := json.Marshal(c)
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			os.Exit(runCacheCommand(os.Args[2:], os.Stdout, os.Stderr))
+		case "import-cep":
+			os.Exit(runImportCommand(os.Args[2:], os.Stdout, os.Stderr))
+		}
 	}
-	log.Print(string(jsonData))
-	w.Write(jsonData)
-}
 
-func tempHandler(w http.ResponseWriter, r *http.Request) {
-	path := strings.Split(r.URL.Path, "/")
-	if len(path) < 3 {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
+	// upstream urls, keys and extra CA bundle come from the environment; the
+	// default client already logged why it could not be configured
+	client := external.DefaultClient()
+	if external.DefaultClientError() != nil {
+		os.Exit(1)
 	}
-	cep := path[2]
-	// remove separator if exists
-	cep = strings.ReplaceAll(cep, "-", "")
-	c, err := CepConcurrency(cep)
-	if err != nil {
-		if err.Error() == "404 can not find zipcode" {
-			w.WriteHeader(http.StatusUnprocessableEntity) // 422
-		}
-		if err.Error() == "can not find zipcode" {
-			w.WriteHeader(http.StatusNotFound) // 404
-		}
-
-		w.Write([]byte(err.Error()))
-		return
+	if !client.HasWeatherApiKey() {
+		log.Fatalf("no WeatherAPI key configured: set %s, %s or %s", external.EnvWeatherApiKey, external.EnvWeatherApiKeys, external.EnvWeatherApiKeyFile)
 	}
 
-	q := strings.Join([]string{utils.RemoveAccents(c.City), utils.RemoveAccents(c.State), "brazil"}, "-")
-
-	temp, err := external.CurrentWeather(q, "pt")
+	opts, err := tempbycep.OptionsFromEnv()
 	if err != nil {
-		fmt.Println(err.Error())
-		w.Write([]byte(err.Error()))
-		return
-
+		log.Fatalf("reading configuration: %v", err)
 	}
+	opts = append(opts, tempbycep.WithClient(client))
 
-	// Set the Content-Type header to application/json
-	w.Header().Set("Content-Type", "application/json")
-
-	tempResponse := tempResponse{
-		//Location: temp.Location,
-		Temp_C: temp.Current.TempC,
-		Temp_F: temp.Current.TempF,
-		Temp_K: temp.Current.TempC + 273,
-	}
-	jsonData, err := json.Marshal(tempResponse)
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	if path := os.Getenv(envCacheFile); path != "" {
+		store, err := cache.OpenFileStore(path)
+		if err != nil {
+			log.Fatalf("opening cache file: %v", err)
+		}
+		// every write goes straight to the file, nothing to flush on exit
+		opts = append(opts, tempbycep.WithPersistentCache(store))
 	}
 
-	log.Print(string(jsonData))
-	w.Write(jsonData)
-}
-
-func CepConcurrency(cep string) (external.Address, error) {
-	c1 := make(chan Result)
-	c2 := make(chan Result)
-
-	go func() {
-		data, err := external.BrasilApiCep(cep)
-		c1 <- Result{Address: data, Err: err}
-	}()
-	go func() {
-		data, err := external.ViaCep(cep)
-		c2 <- Result{Address: data, Err: err}
-	}()
+	svc := tempbycep.New(opts...)
 
-	select {
-	case res := <-c1:
-		if res.Err != nil {
-			return external.Address{}, res.Err
-		}
-		return res.Address, nil
-	case res := <-c2:
-		if res.Err != nil {
-			return external.Address{}, res.Err
-		}
-		return res.Address, nil
-	case <-time.After(time.Second * 1):
-		return external.Address{}, errors.New("Timeout Reached, no API returned in time. CEP: " + cep)
-	}
+	log.Print("Listening...")
+	log.Fatal(http.ListenAndServe(":8080", svc.Handler()))
 }
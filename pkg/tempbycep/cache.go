@@ -0,0 +1,219 @@
+package tempbycep
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cache"
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// CacheConfig sizes a cache. A Size of zero disables it.
+type CacheConfig struct {
+	Size int
+	TTL  time.Duration
+	// NegativeTTL is how long a "CEP does not exist" answer is remembered.
+	NegativeTTL time.Duration
+}
+
+// DefaultAddressCache is used unless WithAddressCache says otherwise. An
+// address almost never changes, a missing CEP may be created later.
+var DefaultAddressCache = CacheConfig{Size: 10000, TTL: 24 * time.Hour, NegativeTTL: time.Hour}
+
+// weatherUpdateInterval is how often WeatherAPI refreshes current conditions.
+const weatherUpdateInterval = 15 * time.Minute
+
+// DefaultWeatherCache is used unless WithWeatherCache says otherwise. TTL is
+// an upper bound, entries live until the next expected WeatherAPI update.
+var DefaultWeatherCache = CacheConfig{Size: 2000, TTL: weatherUpdateInterval}
+
+// WithAddressCache configures the LRU in front of the CEP providers.
+func WithAddressCache(cfg CacheConfig) Option {
+	return func(s *Service) {
+		s.addressCacheConfig = cfg
+	}
+}
+
+// WithWeatherCache configures the cache of current weather, keyed by the
+// normalized location query. NegativeTTL is not used.
+func WithWeatherCache(cfg CacheConfig) Option {
+	return func(s *Service) {
+		s.weatherCacheConfig = cfg
+	}
+}
+
+// WithPersistentCache adds store as a durable second level below the address
+// and weather caches, so cached answers survive restarts.
+func WithPersistentCache(store cache.Store) Option {
+	return func(s *Service) {
+		s.store = store
+	}
+}
+
+// Key prefixes of the entries kept in the persistent store.
+const (
+	AddressKeyPrefix = "address:"
+	WeatherKeyPrefix = "weather:"
+)
+
+// addressEntry is either a resolved address or a definitive not found error.
+type addressEntry struct {
+	address external.Address
+	err     error
+}
+
+// persistedAddress is the form of an addressEntry kept in the persistent store.
+type persistedAddress struct {
+	Address  external.Address `json:"address"`
+	NotFound bool             `json:"not_found,omitempty"`
+}
+
+// CacheStats reports hit and miss counters of the service caches.
+type CacheStats struct {
+	Addresses cache.Stats `json:"addresses"`
+	Weather   cache.Stats `json:"weather"`
+}
+
+func (s *Service) CacheStats() CacheStats {
+	var stats CacheStats
+	if s.addresses != nil {
+		stats.Addresses = s.addresses.Stats()
+	}
+	if s.weather != nil {
+		stats.Weather = s.weather.Stats()
+	}
+	return stats
+}
+
+func (s *Service) cachedAddress(cep string) (addressEntry, bool) {
+	if s.addresses != nil {
+		if entry, ok := s.addresses.Get(cep); ok {
+			return entry, true
+		}
+	}
+	if s.store == nil {
+		return addressEntry{}, false
+	}
+
+	var persisted persistedAddress
+	expiresAt, ok, err := s.store.Get(AddressKeyPrefix+cep, &persisted)
+	if err != nil {
+		log.Printf("reading persistent cache: %v", err)
+	}
+	if !ok {
+		return addressEntry{}, false
+	}
+	entry := addressEntry{address: persisted.Address}
+	if persisted.NotFound {
+		entry = addressEntry{err: utils.ZipNotFoundError}
+	}
+	if s.addresses != nil {
+		s.addresses.Set(cep, entry, time.Until(expiresAt))
+	}
+	return entry, true
+}
+
+func (s *Service) storeAddress(cep string, address external.Address, err error) {
+	var entry addressEntry
+	var persisted persistedAddress
+	var ttl time.Duration
+	switch {
+	case err == nil:
+		entry, persisted, ttl = addressEntry{address: address}, persistedAddress{Address: address}, s.addressCacheConfig.TTL
+	case isNotFound(err):
+		entry, persisted, ttl = addressEntry{err: err}, persistedAddress{NotFound: true}, s.addressCacheConfig.NegativeTTL
+	default:
+		return
+	}
+
+	if s.addresses != nil {
+		s.addresses.Set(cep, entry, ttl)
+	}
+	if s.store != nil {
+		if err := s.store.Set(AddressKeyPrefix+cep, persisted, ttl); err != nil {
+			log.Printf("writing persistent cache: %v", err)
+		}
+	}
+}
+
+// isNotFound reports whether err is a definitive "CEP does not exist". When
+// several providers failed, all of them must agree; a not found next to an
+// outage is not worth remembering.
+func isNotFound(err error) bool {
+	var aggregate *external.AggregateError
+	if errors.As(err, &aggregate) {
+		if len(aggregate.Errors) == 0 {
+			return false
+		}
+		for _, e := range aggregate.Errors {
+			if !errors.Is(e, utils.ErrNotFound) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, utils.ErrNotFound)
+}
+
+// weatherKey normalizes a WeatherAPI query so that "Magé-RJ-brazil" and
+// "mage-rj-Brazil " share one entry.
+func weatherKey(query string, lang string) string {
+	return strings.ToLower(strings.TrimSpace(utils.RemoveAccents(query))) + "|" + lang
+}
+
+func (s *Service) cachedWeather(key string) (external.CurrentModel, bool) {
+	if s.weather != nil {
+		if current, ok := s.weather.Get(key); ok {
+			return current, true
+		}
+	}
+	if s.store == nil {
+		return external.CurrentModel{}, false
+	}
+
+	var current external.CurrentModel
+	expiresAt, ok, err := s.store.Get(WeatherKeyPrefix+key, &current)
+	if err != nil {
+		log.Printf("reading persistent cache: %v", err)
+	}
+	if !ok || current.Current == nil {
+		return external.CurrentModel{}, false
+	}
+	if s.weather != nil {
+		s.weather.Set(key, current, time.Until(expiresAt))
+	}
+	return current, true
+}
+
+func (s *Service) storeWeather(key string, current external.CurrentModel) {
+	ttl := weatherTTL(current.Current, time.Now(), s.weatherCacheConfig.TTL)
+	if s.weather != nil {
+		s.weather.Set(key, current, ttl)
+	}
+	if s.store != nil {
+		if err := s.store.Set(WeatherKeyPrefix+key, current, ttl); err != nil {
+			log.Printf("writing persistent cache: %v", err)
+		}
+	}
+}
+
+// weatherTTL keeps a reading until WeatherAPI is expected to publish the next
+// one, based on last_updated_epoch, and never longer than max.
+func weatherTTL(current *external.Current, now time.Time, max time.Duration) time.Duration {
+	if current == nil || current.LastUpdatedEpoch == 0 {
+		return max
+	}
+	next := time.Unix(int64(current.LastUpdatedEpoch), 0).Add(weatherUpdateInterval)
+	ttl := next.Sub(now)
+	// an overdue update still deserves a short pause before asking again
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+	if ttl > max {
+		return max
+	}
+	return ttl
+}
@@ -0,0 +1,124 @@
+package tempbycep
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+)
+
+// Environment variables read by OptionsFromEnv.
+const (
+	EnvAddressCacheSize        = "ADDRESS_CACHE_SIZE"
+	EnvAddressCacheTTL         = "ADDRESS_CACHE_TTL"
+	EnvAddressCacheNegativeTTL = "ADDRESS_CACHE_NEGATIVE_TTL"
+	EnvWeatherCacheSize        = "WEATHER_CACHE_SIZE"
+	EnvWeatherCacheTTL         = "WEATHER_CACHE_TTL"
+	EnvBreakerThreshold        = "BREAKER_FAILURE_THRESHOLD"
+	EnvBreakerOpenTimeout      = "BREAKER_OPEN_TIMEOUT"
+	EnvLookupStrategy          = "LOOKUP_STRATEGY"
+	EnvCepProviders            = "CEP_PROVIDERS"
+	EnvCepIndexFile            = "CEP_INDEX_FILE"
+)
+
+// OptionsFromEnv builds service options from the environment. Unset variables
+// keep the defaults.
+func OptionsFromEnv() ([]Option, error) {
+	var opts []Option
+
+	addressCache := DefaultAddressCache
+	if err := envInt(EnvAddressCacheSize, &addressCache.Size); err != nil {
+		return nil, err
+	}
+	if err := envDuration(EnvAddressCacheTTL, &addressCache.TTL); err != nil {
+		return nil, err
+	}
+	if err := envDuration(EnvAddressCacheNegativeTTL, &addressCache.NegativeTTL); err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithAddressCache(addressCache))
+
+	weatherCache := DefaultWeatherCache
+	if err := envInt(EnvWeatherCacheSize, &weatherCache.Size); err != nil {
+		return nil, err
+	}
+	if err := envDuration(EnvWeatherCacheTTL, &weatherCache.TTL); err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithWeatherCache(weatherCache))
+
+	breaker := DefaultBreaker
+	if err := envInt(EnvBreakerThreshold, &breaker.FailureThreshold); err != nil {
+		return nil, err
+	}
+	if err := envDuration(EnvBreakerOpenTimeout, &breaker.OpenTimeout); err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithCircuitBreaker(breaker))
+
+	switch strategy := Strategy(os.Getenv(EnvLookupStrategy)); strategy {
+	case "":
+	case StrategyHedge, StrategyRace, StrategyMerge:
+		opts = append(opts, WithStrategy(strategy))
+	default:
+		return nil, fmt.Errorf("%s: unknown strategy %q, expected %s, %s or %s", EnvLookupStrategy, strategy, StrategyHedge, StrategyRace, StrategyMerge)
+	}
+
+	indexFile := os.Getenv(EnvCepIndexFile)
+	if indexFile != "" {
+		ix, err := cepindex.Open(indexFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", EnvCepIndexFile, err)
+		}
+		opts = append(opts, WithLocalIndex(ix))
+	}
+
+	if v := os.Getenv(EnvCepProviders); v != "" {
+		names := strings.Split(v, ",")
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(name), "local") {
+				if indexFile == "" {
+					return nil, fmt.Errorf("%s: the local provider needs %s", EnvCepProviders, EnvCepIndexFile)
+				}
+				continue
+			}
+			if _, err := external.NewBuiltinProvider(name, nil); err != nil {
+				return nil, fmt.Errorf("%s: %v", EnvCepProviders, err)
+			}
+		}
+		opts = append(opts, WithProviders(names...))
+	}
+
+	return opts, nil
+}
+
+func envInt(name string, dst *int) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	*dst = n
+	return nil
+}
+
+// envDuration accepts Go durations such as 30m or 24h.
+func envDuration(name string, dst *time.Duration) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,112 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const (
+	// geocodeCacheSize fits every Brazilian municipality.
+	geocodeCacheSize = 6000
+	// geocodeTTL is long, places do not move. A city WeatherAPI does not know
+	// is asked again sooner.
+	geocodeTTL     = 30 * 24 * time.Hour
+	geocodeMissTTL = time.Hour
+)
+
+// unresolvedLocationError is returned when the coordinates of an address are
+// not known, so there is no place to ask the weather of. The city name alone
+// is not used instead, it often matches a city of another state.
+var unresolvedLocationError = utils.HttpError{
+	Code:    http.StatusBadGateway,
+	Message: "can not find the location of the cep",
+	Kind:    "unresolved_location",
+	Err:     utils.ErrUpstreamUnavailable,
+}
+
+// geocode returns the coordinates of address: the location a provider sent,
+// e.g. BrasilAPI v2, or else the WeatherAPI search match in the same state.
+// It returns unresolvedLocationError when neither is known. Search results,
+// misses included, are cached per city.
+func (s *Service) geocode(ctx context.Context, address external.Address) (*external.Coordinates, error) {
+	if address.Location != nil {
+		return address.Location, nil
+	}
+	if address.City == "" || address.State == "" {
+		return nil, unresolvedLocationError.WithMessage("the address of the cep has no city")
+	}
+	stateName := address.StateName
+	if stateName == "" {
+		stateName, _, _ = utils.StateOfUF(address.State)
+	}
+	key := normalizeText(address.City) + "|" + strings.ToUpper(address.State)
+	location, ok := s.geocodes.Get(key)
+	if !ok {
+		var err error
+		location, err = s.geocodeFlight.Do(ctx, key, func(ctx context.Context) (*external.Coordinates, error) {
+			q := strings.Join([]string{utils.RemoveAccents(address.City), utils.RemoveAccents(stateName), "brazil"}, "-")
+			results, err := s.client.SearchLocations(ctx, q)
+			if err != nil {
+				var detailer utils.ProblemDetailer
+				if !errors.As(err, &detailer) {
+					err = &external.ProviderError{Provider: "weatherAPI", Err: err}
+				}
+				return nil, err
+			}
+			location := matchLocation(address.City, stateName, results)
+			ttl := geocodeTTL
+			if location == nil {
+				ttl = geocodeMissTTL
+			}
+			s.geocodes.Set(key, location, ttl)
+			return location, nil
+		})
+		if err != nil {
+			log.Printf("geocoding %s: %v", key, err)
+			return nil, err
+		}
+	}
+	if location == nil {
+		return nil, unresolvedLocationError.WithMessage(fmt.Sprintf("can not find %s - %s in the weather provider", address.City, address.State))
+	}
+	return location, nil
+}
+
+// matchLocation picks the search result in Brazil whose region is the state,
+// preferring one named after the city. Results of other states are never
+// used, as a city name alone is often ambiguous.
+func matchLocation(city string, stateName string, results []external.WeatherLocation) *external.Coordinates {
+	var match *external.WeatherLocation
+	for i, r := range results {
+		if normalizeText(r.Country) != "brazil" || normalizeText(r.Region) != normalizeText(stateName) {
+			continue
+		}
+		if normalizeText(r.Name) == normalizeText(city) {
+			match = &results[i]
+			break
+		}
+		if match == nil {
+			match = &results[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	return &external.Coordinates{Latitude: widen(match.Lat), Longitude: widen(match.Lon)}
+}
+
+// widen converts f keeping its shortest decimal form, -22.66 and not
+// -22.659999847.
+func widen(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'f', -1, 32), 64)
+	return v
+}
@@ -0,0 +1,196 @@
+package tempbycep
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// invalidUrlError is returned when the path has no CEP segment.
+var invalidUrlError = utils.HttpError{Code: http.StatusBadRequest, Message: "invalid url", Kind: "invalid_url", Err: utils.ErrInvalidCep}
+
+// invalidFieldsError is returned when ?fields= names an unknown field.
+var invalidFieldsError = utils.HttpError{Code: http.StatusBadRequest, Message: "invalid fields", Kind: "invalid_fields", Err: utils.ErrInvalidQuery}
+
+// addressFieldNames are the JSON names of the fields of an Address, the
+// names ?fields= accepts.
+var addressFieldNames = jsonFieldNames(reflect.TypeOf(external.Address{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFields reads a comma separated field mask, e.g. "cep,city,ibge". An
+// empty mask keeps every field.
+func parseFields(mask string) ([]string, error) {
+	var fields []string
+	for _, name := range strings.Split(mask, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !addressFieldNames[name] {
+			return nil, invalidFieldsError.WithMessage("unknown field " + name)
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// maskFields keeps only the named fields of the JSON form of v.
+func maskFields(v any, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	masked := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := all[name]; ok {
+			masked[name] = value
+		}
+	}
+	return masked, nil
+}
+
+// Handler returns the HTTP API of the service: /cep/{cep}?fields=, /temp/{cep},
+// /search/cep, /admin/cache, /admin/providers and a pong on every other path.
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cep/", s.cepHandler)
+	mux.HandleFunc("/temp/", s.tempHandler)
+	mux.HandleFunc("/search/cep", s.searchHandler)
+	mux.HandleFunc("/admin/cache", s.cacheStatsHandler)
+	mux.HandleFunc("/admin/providers", s.providerStatusHandler)
+	mux.HandleFunc("/", homeHandler)
+	return mux
+}
+
+func homeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("pong"))
+}
+
+// writeProblem is the single error rendering path of the handlers.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error, cep string) {
+	log.Print(err.Error())
+	p := utils.NewProblem(err)
+	p.Instance = r.URL.Path
+	p.Cep = cep
+	p.Write(w)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v any, cep string) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		writeProblem(w, r, err, cep)
+		return
+	}
+	log.Print(string(jsonData))
+	// Set the Content-Type header to application/json
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// cepFromPath returns the second path segment, e.g. /cep/{cep}.
+func cepFromPath(path string) (string, bool) {
+	segments := strings.Split(path, "/")
+	if len(segments) < 3 {
+		return "", false
+	}
+	// remove separator if exists
+	return strings.ReplaceAll(segments[2], "-", ""), true
+}
+
+func (s *Service) cepHandler(w http.ResponseWriter, r *http.Request) {
+	cep, ok := cepFromPath(r.URL.Path)
+	if !ok {
+		writeProblem(w, r, invalidUrlError, "")
+		return
+	}
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		writeProblem(w, r, err, cep)
+		return
+	}
+	c, err := s.LookupAddress(r.Context(), cep)
+	if err != nil {
+		writeProblem(w, r, err, cep)
+		return
+	}
+	if len(fields) == 0 {
+		writeJSON(w, r, c, cep)
+		return
+	}
+	masked, err := maskFields(c, fields)
+	if err != nil {
+		writeProblem(w, r, err, cep)
+		return
+	}
+	writeJSON(w, r, masked, cep)
+}
+
+func (s *Service) tempHandler(w http.ResponseWriter, r *http.Request) {
+	cep, ok := cepFromPath(r.URL.Path)
+	if !ok {
+		writeProblem(w, r, invalidUrlError, "")
+		return
+	}
+	temp, err := s.CurrentTemperature(r.Context(), cep)
+	if err != nil {
+		writeProblem(w, r, err, cep)
+		return
+	}
+	writeJSON(w, r, temp, cep)
+}
+
+// searchHandler serves /search/cep?uf=&city=&street=&page=&page_size=.
+func (s *Service) searchHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := external.SearchQuery{State: params.Get("uf"), City: params.Get("city"), Street: params.Get("street")}
+	page, pageSize := 1, 0
+	// checked in a fixed order, so a request with both wrong always gets the
+	// same problem
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"page", &page}, {"page_size", &pageSize}} {
+		if v := params.Get(param.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeProblem(w, r, utils.InvalidSearchError.WithMessage(param.name+" must be a positive number"), "")
+				return
+			}
+			*param.target = n
+		}
+	}
+	result, err := s.SearchAddresses(r.Context(), q, page, pageSize)
+	if err != nil {
+		writeProblem(w, r, err, "")
+		return
+	}
+	writeJSON(w, r, result, "")
+}
+
+func (s *Service) cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, s.CacheStats(), "")
+}
+
+func (s *Service) providerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, s.ProviderStatus(), "")
+}
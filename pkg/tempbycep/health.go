@@ -0,0 +1,299 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// BreakerConfig tunes the circuit breaker kept for every CEP provider. A
+// FailureThreshold of zero disables the breaker; health is still tracked.
+type BreakerConfig struct {
+	// FailureThreshold is how many failures in a row open the circuit.
+	FailureThreshold int
+	// OpenTimeout is how long an open circuit skips the provider before a
+	// single half-open probe is let through.
+	OpenTimeout time.Duration
+}
+
+// DefaultBreaker is used unless WithCircuitBreaker says otherwise.
+var DefaultBreaker = BreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second}
+
+// WithCircuitBreaker configures the per provider circuit breaker.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(s *Service) {
+		s.health.config = cfg
+	}
+}
+
+// Circuit states reported by ProviderStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// circuitOpenError is returned when every provider is skipped by its breaker.
+var circuitOpenError = utils.HttpError{Code: http.StatusServiceUnavailable, Message: "every cep provider is failing, retry later", Kind: "circuit_open", Err: utils.ErrUpstreamUnavailable}
+
+// healthWindow is how many recent calls the success rate and latency cover.
+const healthWindow = 100
+
+// ProviderStatus is the health of one provider as served by /admin/providers.
+type ProviderStatus struct {
+	Name                string `json:"name"`
+	State               string `json:"state"`
+	Successes           int64  `json:"successes"`
+	Failures            int64  `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	// SuccessRate and the latencies cover the last calls only; the p90 only
+	// counts successful calls, as the hedging delay does.
+	SuccessRate  float64    `json:"success_rate"`
+	LatencyAvgMs float64    `json:"latency_avg_ms"`
+	LatencyP90Ms float64    `json:"latency_p90_ms"`
+	OpenUntil    *time.Time `json:"open_until,omitempty"`
+}
+
+// providerHealth is the breaker and the recent calls of one provider.
+type providerHealth struct {
+	state               string
+	openUntil           time.Time
+	probing             bool
+	successes, failures int64
+	consecutiveFailures int
+
+	// ring buffers of the last healthWindow calls
+	outcomes  []bool
+	latencies []time.Duration
+	next      int
+}
+
+func (h *providerHealth) push(ok bool, latency time.Duration) {
+	if len(h.outcomes) < healthWindow {
+		h.outcomes = append(h.outcomes, ok)
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.outcomes[h.next] = ok
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % healthWindow
+}
+
+// percentile returns the p-th percentile of latencies, sorting them in place.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[int(p*float64(len(latencies)-1))]
+}
+
+// successLatencies returns the latencies of the recent successful calls.
+func (h *providerHealth) successLatencies() []time.Duration {
+	var out []time.Duration
+	for i, ok := range h.outcomes {
+		if ok {
+			out = append(out, h.latencies[i])
+		}
+	}
+	return out
+}
+
+// healthTracker records the outcome of every provider call and decides which
+// providers a lookup may call.
+type healthTracker struct {
+	mu        sync.Mutex
+	config    BreakerConfig
+	providers map[string]*providerHealth
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		config:    DefaultBreaker,
+		providers: make(map[string]*providerHealth),
+		now:       time.Now,
+	}
+}
+
+func (t *healthTracker) get(name string) *providerHealth {
+	h, ok := t.providers[name]
+	if !ok {
+		h = &providerHealth{state: CircuitClosed}
+		t.providers[name] = h
+	}
+	return h
+}
+
+// available reports whether allow would let a call through, without taking
+// the half-open probe.
+func (t *healthTracker) available(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.get(name)
+	switch h.state {
+	case CircuitOpen:
+		return !t.now().Before(h.openUntil)
+	case CircuitHalfOpen:
+		return !h.probing
+	}
+	return true
+}
+
+// allow reports whether a call to the provider may go out. Once the open
+// timeout elapsed, a single caller is let through as the half-open probe,
+// which is held until record is called. Call it only right before the call.
+func (t *healthTracker) allow(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.get(name)
+	switch h.state {
+	case CircuitOpen:
+		if t.now().Before(h.openUntil) {
+			return false
+		}
+		h.state = CircuitHalfOpen
+		h.probing = true
+		return true
+	case CircuitHalfOpen:
+		if h.probing {
+			return false
+		}
+		h.probing = true
+		return true
+	}
+	return true
+}
+
+// record updates the provider after a call that took latency and ended with err.
+func (t *healthTracker) record(name string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.get(name)
+	h.probing = false
+
+	ok, counts := callOutcome(err)
+	if !counts {
+		return
+	}
+	h.push(ok, latency)
+	if ok {
+		h.successes++
+		h.consecutiveFailures = 0
+		h.state = CircuitClosed
+		return
+	}
+	h.failures++
+	h.consecutiveFailures++
+	if t.config.FailureThreshold > 0 && (h.state == CircuitHalfOpen || h.consecutiveFailures >= t.config.FailureThreshold) {
+		h.state = CircuitOpen
+		h.openUntil = t.now().Add(t.config.OpenTimeout)
+	}
+}
+
+// callOutcome tells whether err means the provider is healthy. A not found,
+// an invalid CEP or an invalid search is a proper answer; a call cancelled
+// because another provider won says nothing and is not counted.
+func callOutcome(err error) (ok bool, counts bool) {
+	switch {
+	case err == nil, errors.Is(err, utils.ErrNotFound), errors.Is(err, utils.ErrInvalidCep), errors.Is(err, utils.ErrInvalidQuery):
+		return true, true
+	case errors.Is(err, context.Canceled):
+		return false, false
+	}
+	return false, true
+}
+
+// p90 returns the p90 latency of the recent successful calls of the provider,
+// once there are at least min of them.
+func (t *healthTracker) p90(name string, min int) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	latencies := t.get(name).successLatencies()
+	if len(latencies) < min {
+		return 0, false
+	}
+	return percentile(latencies, 0.9), true
+}
+
+func (t *healthTracker) status(name string) ProviderStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h := t.get(name)
+	status := ProviderStatus{
+		Name:                name,
+		State:               h.state,
+		Successes:           h.successes,
+		Failures:            h.failures,
+		ConsecutiveFailures: h.consecutiveFailures,
+		LatencyP90Ms:        float64(percentile(h.successLatencies(), 0.9)) / float64(time.Millisecond),
+	}
+	if h.state == CircuitOpen {
+		openUntil := h.openUntil
+		status.OpenUntil = &openUntil
+	}
+	if n := len(h.outcomes); n > 0 {
+		var good int
+		var total time.Duration
+		for i, ok := range h.outcomes {
+			if ok {
+				good++
+			}
+			total += h.latencies[i]
+		}
+		status.SuccessRate = float64(good) / float64(n)
+		status.LatencyAvgMs = float64(total) / float64(n) / float64(time.Millisecond)
+	}
+	return status
+}
+
+// trackedProvider asks the breaker before every call of the wrapped provider
+// and reports the outcome to the tracker. The half-open probe is only taken
+// by a call that really goes out, so a provider a hedged lookup never got to
+// does not hold it.
+type trackedProvider struct {
+	external.CepProvider
+	health *healthTracker
+}
+
+func (p trackedProvider) Lookup(ctx context.Context, cep string) (external.Address, error) {
+	if !p.health.allow(p.Name()) {
+		return external.Address{}, circuitOpenError
+	}
+	start := time.Now()
+	address, err := p.CepProvider.Lookup(ctx, cep)
+	p.health.record(p.Name(), time.Since(start), err)
+	return address, err
+}
+
+// availableProviders returns the registered providers whose circuit would let
+// a call through, wrapped for health tracking and state checking. An answer
+// with the wrong state counts as a failure of its provider.
+func (s *Service) availableProviders() []external.CepProvider {
+	var out []external.CepProvider
+	for _, p := range s.registry.Providers() {
+		if s.health.available(p.Name()) {
+			out = append(out, trackedProvider{CepProvider: rangeCheckedProvider{p}, health: s.health})
+		}
+	}
+	return out
+}
+
+// ProviderStatus reports the circuit state and recent health of every
+// registered provider, in registry order.
+func (s *Service) ProviderStatus() []ProviderStatus {
+	providers := s.registry.Providers()
+	out := make([]ProviderStatus, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, s.health.status(p.Name()))
+	}
+	return out
+}
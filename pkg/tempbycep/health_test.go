@@ -0,0 +1,140 @@
+package tempbycep
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func TestHealthTrackerBreaker(t *testing.T) {
+	now := time.Now()
+	tracker := newHealthTracker()
+	tracker.config = BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}
+	tracker.now = func() time.Time { return now }
+
+	// not found is an answer, a cancelled loser is no answer at all
+	tracker.record("p", time.Millisecond, utils.ZipNotFoundError)
+	tracker.record("p", time.Millisecond, context.Canceled)
+	tracker.record("p", time.Millisecond, utils.UpstreamError)
+	if !tracker.allow("p") {
+		t.Fatalf("circuit opened before the threshold")
+	}
+	tracker.record("p", time.Millisecond, utils.UpstreamError)
+	if tracker.allow("p") {
+		t.Fatalf("circuit still closed after %d failures in a row", 2)
+	}
+	if status := tracker.status("p"); status.State != CircuitOpen || status.Successes != 1 || status.Failures != 2 || status.OpenUntil == nil {
+		t.Errorf("status() = %+v", status)
+	}
+
+	// a single probe once the timeout elapsed
+	now = now.Add(time.Minute)
+	if !tracker.allow("p") {
+		t.Fatalf("no half-open probe after the open timeout")
+	}
+	if tracker.allow("p") {
+		t.Errorf("a second call went out while the probe is pending")
+	}
+	// a failed probe opens the circuit again
+	tracker.record("p", time.Millisecond, utils.TimeoutError)
+	if tracker.allow("p") {
+		t.Fatalf("circuit closed after a failed probe")
+	}
+
+	now = now.Add(time.Minute)
+	tracker.allow("p")
+	tracker.record("p", time.Millisecond, nil)
+	if status := tracker.status("p"); status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("status() after a good probe = %+v", status)
+	}
+}
+
+func TestLookupAddressSkipsOpenCircuits(t *testing.T) {
+	down := &countingProvider{stubProvider: stubProvider{name: "down", err: utils.UpstreamError}}
+	up := &countingProvider{stubProvider: stubProvider{name: "up", delay: 10 * time.Millisecond, addr: external.Address{Cep: "20541155"}}}
+	svc := New(
+		WithRegistry(external.NewRegistry(down, up)),
+		WithAddressCache(CacheConfig{}),
+		WithCircuitBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Hour}),
+	)
+
+	for i := 0; i < 5; i++ {
+		if _, err := svc.LookupAddress(context.Background(), "20541155"); err != nil {
+			t.Fatalf("LookupAddress() returned an error: %v", err)
+		}
+	}
+	if down.calls != 3 {
+		t.Errorf("open provider was called %d times, expected 3", down.calls)
+	}
+
+	svc.Registry().Remove("up")
+	_, err := svc.LookupAddress(context.Background(), "20541155")
+	var httpErr utils.HttpError
+	if !errors.As(err, &httpErr) || httpErr.Kind != "circuit_open" || !errors.Is(err, utils.ErrUpstreamUnavailable) {
+		t.Errorf("LookupAddress() with every circuit open returned %v", err)
+	}
+}
+
+func TestHedgedLookupKeepsUnlaunchedProbe(t *testing.T) {
+	a := &countingProvider{stubProvider: stubProvider{name: "a", addr: external.Address{Cep: "20541155"}}}
+	b := &countingProvider{stubProvider: stubProvider{name: "b", addr: external.Address{Cep: "20541155"}}}
+	svc := New(
+		WithRegistry(external.NewRegistry(a, b)),
+		WithAddressCache(CacheConfig{}),
+		WithCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	)
+	now := time.Now()
+	svc.health.now = func() time.Time { return now }
+	svc.health.record("b", time.Millisecond, utils.UpstreamError)
+	now = now.Add(time.Minute)
+
+	// b is due for a probe, but a answers before the hedge delay every time
+	for i := 0; i < 3; i++ {
+		if _, err := svc.LookupAddress(context.Background(), "20541155"); err != nil {
+			t.Fatalf("LookupAddress() returned an error: %v", err)
+		}
+	}
+	if b.calls != 0 {
+		t.Fatalf("b was called %d times, expected the hedge to stop at a", b.calls)
+	}
+
+	// the probe was never taken, so the next lookup without a may use it
+	svc.Registry().Remove("a")
+	if _, err := svc.LookupAddress(context.Background(), "20541155"); err != nil {
+		t.Fatalf("LookupAddress() with only the half-open provider returned %v", err)
+	}
+	if status := svc.health.status("b"); b.calls != 1 || status.State != CircuitClosed {
+		t.Errorf("b called %d times, status %+v", b.calls, status)
+	}
+}
+
+func TestProviderStatusHandler(t *testing.T) {
+	ok := &stubProvider{name: "ok", addr: external.Address{Cep: "20541155"}}
+	idle := &stubProvider{name: "idle"}
+	svc := New(WithRegistry(external.NewRegistry(ok, idle)))
+	svc.health.record("ok", 20*time.Millisecond, nil)
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/providers", nil))
+
+	var statuses []ProviderStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if len(statuses) != 2 || statuses[0].Name != "ok" || statuses[1].Name != "idle" {
+		t.Fatalf("/admin/providers = %+v", statuses)
+	}
+	if statuses[0].State != CircuitClosed || statuses[0].SuccessRate != 1 || statuses[0].LatencyP90Ms != 20 {
+		t.Errorf("status of ok = %+v", statuses[0])
+	}
+	if statuses[1].Successes != 0 || statuses[1].SuccessRate != 0 {
+		t.Errorf("status of a provider never called = %+v", statuses[1])
+	}
+}
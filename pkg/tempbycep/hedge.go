@@ -0,0 +1,138 @@
+package tempbycep
+
+import (
+	"context"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+)
+
+// Strategy decides how LookupAddress spreads a lookup over the providers.
+type Strategy string
+
+const (
+	// StrategyHedge calls the providers one at a time in registry order and
+	// only moves on when the current one fails or is slower than usual.
+	StrategyHedge Strategy = "hedge"
+	// StrategyRace calls every provider at once and takes the first answer.
+	StrategyRace Strategy = "race"
+)
+
+// WithStrategy selects how providers are called. The default is StrategyHedge;
+// it used to be StrategyRace, so pass it to keep calling every provider at once.
+func WithStrategy(strategy Strategy) Option {
+	return func(s *Service) {
+		s.strategy = strategy
+	}
+}
+
+// HedgeConfig bounds how long StrategyHedge waits for a provider before
+// firing the next one. The wait is the p90 latency of its recent successful
+// calls.
+type HedgeConfig struct {
+	// InitialDelay is used until a provider has enough latency samples.
+	InitialDelay time.Duration
+	MinDelay     time.Duration
+	MaxDelay     time.Duration
+}
+
+// DefaultHedge is used unless WithHedge says otherwise.
+var DefaultHedge = HedgeConfig{InitialDelay: 300 * time.Millisecond, MinDelay: 20 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+
+// WithHedge tunes the delays of StrategyHedge.
+func WithHedge(cfg HedgeConfig) Option {
+	return func(s *Service) {
+		s.hedge = cfg
+	}
+}
+
+// minHedgeSamples is how many successful calls a provider needs before its
+// p90 replaces the initial delay.
+const minHedgeSamples = 5
+
+// hedgeDelay is how long to wait for the provider named name.
+func (s *Service) hedgeDelay(name string) time.Duration {
+	d, ok := s.health.p90(name, minHedgeSamples)
+	if !ok {
+		d = s.hedge.InitialDelay
+	}
+	if d < s.hedge.MinDelay {
+		d = s.hedge.MinDelay
+	}
+	if s.hedge.MaxDelay > 0 && d > s.hedge.MaxDelay {
+		d = s.hedge.MaxDelay
+	}
+	return d
+}
+
+// fanOut runs the lookup with the configured strategy.
+func (s *Service) fanOut(ctx context.Context, providers []external.CepProvider, cep string) (external.Address, error) {
+	switch s.strategy {
+	case StrategyRace:
+		return raceProviders(ctx, providers, cep)
+	case StrategyMerge:
+		return mergeProviders(ctx, providers, cep)
+	}
+	return hedgeProviders(ctx, providers, cep, s.hedgeDelay)
+}
+
+// hedgeProviders calls providers in order. The next provider is fired as soon
+// as the previous one fails or once delay(previous) elapsed without an answer;
+// earlier calls keep running and the first success wins. Like raceProviders,
+// it returns an *external.AggregateError when all of them fail and cancels
+// the calls still running once a winner is found.
+func hedgeProviders(ctx context.Context, providers []external.CepProvider, cep string, delay func(name string) time.Duration) (external.Address, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so that goroutines finishing after the lookup never block
+	c := make(chan Result, len(providers))
+	launched, pending := 0, 0
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+
+	launch := func() {
+		p := providers[launched]
+		launched++
+		pending++
+		go func() {
+			data, err := p.Lookup(ctx, cep)
+			if err != nil {
+				err = &external.ProviderError{Provider: p.Name(), Err: err}
+			}
+			c <- Result{Address: data, Err: err}
+		}()
+		if launched < len(providers) {
+			timer.Stop()
+			select {
+			case <-timer.C:
+			default:
+			}
+			timer.Reset(delay(p.Name()))
+		}
+	}
+	launch()
+
+	aggregate := &external.AggregateError{Cep: cep}
+	for pending > 0 {
+		select {
+		case res := <-c:
+			pending--
+			if res.Err == nil {
+				return res.Address, nil
+			}
+			aggregate.Errors = append(aggregate.Errors, res.Err)
+			if launched < len(providers) {
+				launch()
+			}
+		case <-timer.C:
+			if launched < len(providers) {
+				launch()
+			}
+		case <-ctx.Done():
+			return external.Address{}, ctx.Err()
+		}
+	}
+	return external.Address{}, aggregate
+}
@@ -0,0 +1,95 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func fixedDelay(d time.Duration) func(string) time.Duration {
+	return func(string) time.Duration { return d }
+}
+
+func TestHedgeProvidersPreferredAnswers(t *testing.T) {
+	preferred := &countingProvider{stubProvider: stubProvider{name: "preferred", delay: 5 * time.Millisecond, addr: external.Address{Source: "preferred"}}}
+	backup := &countingProvider{stubProvider: stubProvider{name: "backup", addr: external.Address{Source: "backup"}}}
+
+	result, err := hedgeProviders(context.Background(), []external.CepProvider{preferred, backup}, "20541155", fixedDelay(time.Second))
+	if err != nil || result.Source != "preferred" {
+		t.Fatalf("hedgeProviders() = %+v, %v", result, err)
+	}
+	if calls := atomic.LoadInt32(&backup.calls); calls != 0 {
+		t.Errorf("backup called %d times while the preferred provider answered in time", calls)
+	}
+}
+
+func TestHedgeProvidersFiresNextAfterDelay(t *testing.T) {
+	slow := &stubProvider{name: "slow", delay: time.Minute, cancelled: make(chan struct{})}
+	backup := &stubProvider{name: "backup", addr: external.Address{Source: "backup"}}
+
+	start := time.Now()
+	result, err := hedgeProviders(context.Background(), []external.CepProvider{slow, backup}, "20541155", fixedDelay(20*time.Millisecond))
+	if err != nil || result.Source != "backup" {
+		t.Fatalf("hedgeProviders() = %+v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("backup fired after %v, before the hedge delay", elapsed)
+	}
+	select {
+	case <-slow.cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("slow provider was not cancelled after the backup won")
+	}
+}
+
+func TestHedgeProvidersFiresNextOnFailure(t *testing.T) {
+	failing := &stubProvider{name: "failing", err: utils.UpstreamError}
+	backup := &stubProvider{name: "backup", addr: external.Address{Source: "backup"}}
+
+	start := time.Now()
+	result, err := hedgeProviders(context.Background(), []external.CepProvider{failing, backup}, "20541155", fixedDelay(time.Minute))
+	if err != nil || result.Source != "backup" {
+		t.Fatalf("hedgeProviders() = %+v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("backup waited %v for the hedge delay after a failure", elapsed)
+	}
+
+	_, err = hedgeProviders(context.Background(), []external.CepProvider{failing, &stubProvider{name: "missing", err: utils.ZipNotFoundError}}, "20541155", fixedDelay(time.Minute))
+	var aggregate *external.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 2 {
+		t.Errorf("hedgeProviders() with every provider failing returned %v", err)
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	svc := New(WithHedge(HedgeConfig{InitialDelay: 300 * time.Millisecond, MinDelay: 20 * time.Millisecond, MaxDelay: 500 * time.Millisecond}))
+
+	if d := svc.hedgeDelay("p"); d != 300*time.Millisecond {
+		t.Errorf("hedgeDelay() without samples = %v", d)
+	}
+	for i := 1; i <= 10; i++ {
+		svc.health.record("p", time.Duration(i)*10*time.Millisecond, nil)
+	}
+	// failures do not count towards the latency of a provider
+	svc.health.record("p", time.Minute, utils.UpstreamError)
+	if d := svc.hedgeDelay("p"); d != 90*time.Millisecond {
+		t.Errorf("hedgeDelay() = %v, expected the p90 of 90ms", d)
+	}
+
+	for i := 0; i < 10; i++ {
+		svc.health.record("fast", time.Millisecond, nil)
+		svc.health.record("slow", 2*time.Second, nil)
+	}
+	if d := svc.hedgeDelay("fast"); d != 20*time.Millisecond {
+		t.Errorf("hedgeDelay() = %v, expected the minimum", d)
+	}
+	if d := svc.hedgeDelay("slow"); d != 500*time.Millisecond {
+		t.Errorf("hedgeDelay() = %v, expected the maximum", d)
+	}
+}
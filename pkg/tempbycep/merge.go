@@ -0,0 +1,146 @@
+package tempbycep
+
+import (
+	"context"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// StrategyMerge calls every provider at once, waits for all of them within
+// the lookup timeout and merges their answers field by field.
+const StrategyMerge Strategy = "merge"
+
+// addressFields lists the merged fields of a, by JSON name.
+func addressFields(a *external.Address) []struct {
+	name  string
+	value *string
+} {
+	return []struct {
+		name  string
+		value *string
+	}{
+		{"cep", &a.Cep},
+		{"state", &a.State},
+		{"city", &a.City},
+		{"neighborhood", &a.Neighborhood},
+		{"street", &a.Street},
+		{"complement", &a.Complement},
+		{"ibge", &a.Ibge},
+		{"ddd", &a.Ddd},
+		{"gia", &a.Gia},
+		{"siafi", &a.Siafi},
+		{"state_name", &a.StateName},
+		{"region", &a.Region},
+	}
+}
+
+// sameValue compares field values ignoring case, accents, spacing and the CEP
+// separator, so "Andaraí" and "ANDARAI" do not count as a conflict.
+func sameValue(a, b string) bool {
+	return normalizeText(strings.ReplaceAll(a, "-", "")) == normalizeText(strings.ReplaceAll(b, "-", ""))
+}
+
+// normalizeText lowers s and removes its accents and extra spaces.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(utils.RemoveAccents(s))), " ")
+}
+
+// mergeAddresses merges answers given in order of preference. Each field
+// takes the first non empty value; FieldSources records the provider that
+// supplied it and Conflicts every field on which providers disagree. The
+// location is taken whole from the first answer that has one.
+func mergeAddresses(names []string, addresses []external.Address) external.Address {
+	var merged external.Address
+	merged.FieldSources = make(map[string]string)
+	var sources []string
+
+	for i := range addresses {
+		used := false
+		fields := addressFields(&addresses[i])
+		for j, field := range addressFields(&merged) {
+			v := *fields[j].value
+			if v == "" {
+				continue
+			}
+			if *field.value == "" {
+				*field.value = v
+				merged.FieldSources[field.name] = names[i]
+				used = true
+				continue
+			}
+			if !sameValue(*field.value, v) {
+				merged.AddConflict(field.name, merged.FieldSources[field.name], *field.value)
+				merged.AddConflict(field.name, names[i], v)
+			}
+		}
+		if merged.Location == nil && addresses[i].Location != nil {
+			merged.Location = addresses[i].Location
+			merged.FieldSources["location"] = names[i]
+			used = true
+		}
+		if used {
+			sources = append(sources, names[i])
+		}
+	}
+	merged.Source = strings.Join(sources, ",")
+	return merged
+}
+
+// mergeProviders calls every provider and merges the successful answers in
+// registry order. Providers still running when ctx expires are left out;
+// without any answer it fails like raceProviders.
+func mergeProviders(ctx context.Context, providers []external.CepProvider, cep string) (external.Address, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexed struct {
+		i int
+		Result
+	}
+	// buffered so that goroutines finishing after the lookup never block
+	c := make(chan indexed, len(providers))
+	for i, p := range providers {
+		go func(i int, p external.CepProvider) {
+			data, err := p.Lookup(ctx, cep)
+			if err != nil {
+				err = &external.ProviderError{Provider: p.Name(), Err: err}
+			}
+			c <- indexed{i: i, Result: Result{Address: data, Err: err}}
+		}(i, p)
+	}
+
+	results := make([]*Result, len(providers))
+	aggregate := &external.AggregateError{Cep: cep}
+	expired := false
+collect:
+	for range providers {
+		select {
+		case res := <-c:
+			results[res.i] = &res.Result
+			if res.Err != nil {
+				aggregate.Errors = append(aggregate.Errors, res.Err)
+			}
+		case <-ctx.Done():
+			expired = true
+			break collect
+		}
+	}
+
+	var names []string
+	var addresses []external.Address
+	for i, res := range results {
+		if res != nil && res.Err == nil {
+			names = append(names, providers[i].Name())
+			addresses = append(addresses, res.Address)
+		}
+	}
+	switch {
+	case len(addresses) > 0:
+		return mergeAddresses(names, addresses), nil
+	case expired:
+		return external.Address{}, ctx.Err()
+	}
+	return external.Address{}, aggregate
+}
@@ -0,0 +1,74 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func TestMergeProviders(t *testing.T) {
+	brasil := &stubProvider{name: "brasilAPI", delay: 10 * time.Millisecond, addr: external.Address{Cep: "20541155", State: "RJ", City: "Rio de Janeiro", Street: "Rua Paula Brito"}}
+	via := &stubProvider{name: "ViaCEP", addr: external.Address{Cep: "20541-155", State: "RJ", City: "Niterói", Neighborhood: "Andaraí", Street: "RUA PAULA BRITO"}}
+	down := &stubProvider{name: "down", err: utils.UpstreamError}
+
+	result, err := mergeProviders(context.Background(), []external.CepProvider{brasil, down, via}, "20541155")
+	if err != nil {
+		t.Fatalf("mergeProviders() returned an error: %v", err)
+	}
+
+	// the preferred provider wins, the others fill the gaps
+	if result.City != "Rio de Janeiro" || result.Neighborhood != "Andaraí" || result.Source != "brasilAPI,ViaCEP" {
+		t.Errorf("mergeProviders() = %+v", result)
+	}
+	if result.FieldSources["city"] != "brasilAPI" || result.FieldSources["neighborhood"] != "ViaCEP" {
+		t.Errorf("FieldSources = %v", result.FieldSources)
+	}
+	// differences in case, accents or the CEP separator are not conflicts
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "city" {
+		t.Fatalf("Conflicts = %+v, expected only city", result.Conflicts)
+	}
+	if values := result.Conflicts[0].Values; values["brasilAPI"] != "Rio de Janeiro" || values["ViaCEP"] != "Niterói" {
+		t.Errorf("city conflict values = %v", values)
+	}
+}
+
+func TestMergeAddressesDetails(t *testing.T) {
+	location := &external.Coordinates{Latitude: -22.92, Longitude: -43.25}
+	merged := mergeAddresses([]string{"ViaCEP", "brasilAPI"}, []external.Address{
+		{Cep: "20541155", Ibge: "3304557", Ddd: "21"},
+		{Cep: "20541155", Ibge: "3304558", Location: location},
+	})
+	if merged.Ibge != "3304557" || merged.Ddd != "21" || merged.Location != location {
+		t.Errorf("mergeAddresses() = %+v", merged)
+	}
+	if merged.FieldSources["location"] != "brasilAPI" || merged.Source != "ViaCEP,brasilAPI" {
+		t.Errorf("FieldSources = %v, Source = %q", merged.FieldSources, merged.Source)
+	}
+	if len(merged.Conflicts) != 1 || merged.Conflicts[0].Field != "ibge" {
+		t.Errorf("Conflicts = %+v, expected only ibge", merged.Conflicts)
+	}
+}
+
+func TestMergeProvidersPartialAndFailures(t *testing.T) {
+	fast := &stubProvider{name: "fast", addr: external.Address{Cep: "20541155", City: "Rio de Janeiro"}}
+	slow := &stubProvider{name: "slow", delay: time.Minute, addr: external.Address{Street: "never"}}
+
+	// whatever arrived before the deadline is returned
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	result, err := mergeProviders(ctx, []external.CepProvider{slow, fast}, "20541155")
+	if err != nil || result.City != "Rio de Janeiro" || result.Street != "" || result.Source != "fast" {
+		t.Errorf("mergeProviders() past the deadline = %+v, %v", result, err)
+	}
+
+	missing := &stubProvider{name: "missing", err: utils.ZipNotFoundError}
+	_, err = mergeProviders(context.Background(), []external.CepProvider{missing, &stubProvider{name: "down", err: utils.UpstreamError}}, "20541155")
+	var aggregate *external.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 2 {
+		t.Errorf("mergeProviders() with every provider failing returned %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package tempbycep
+
+import (
+	"context"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+)
+
+type Result struct {
+	Address external.Address
+	Err     error
+}
+
+// raceProviders returns the first successful answer among providers. Failed
+// providers do not end the race; when all of them fail their errors are
+// returned together as an *external.AggregateError. The losers are cancelled
+// through ctx once a winner is found.
+func raceProviders(ctx context.Context, providers []external.CepProvider, cep string) (external.Address, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so that goroutines finishing after the race never block
+	c := make(chan Result, len(providers))
+	for _, p := range providers {
+		go func(p external.CepProvider) {
+			data, err := p.Lookup(ctx, cep)
+			if err != nil {
+				err = &external.ProviderError{Provider: p.Name(), Err: err}
+			}
+			c <- Result{Address: data, Err: err}
+		}(p)
+	}
+
+	aggregate := &external.AggregateError{Cep: cep}
+	for range providers {
+		select {
+		case res := <-c:
+			if res.Err == nil {
+				return res.Address, nil
+			}
+			aggregate.Errors = append(aggregate.Errors, res.Err)
+		case <-ctx.Done():
+			return external.Address{}, ctx.Err()
+		}
+	}
+	return external.Address{}, aggregate
+}
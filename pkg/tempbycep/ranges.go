@@ -0,0 +1,52 @@
+package tempbycep
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// inconsistentStateError is returned for an answer whose state does not own
+// the CEP, i.e. the provider has bad data for it.
+var inconsistentStateError = utils.HttpError{
+	Code:    http.StatusBadGateway,
+	Message: "provider returned a state outside the cep range",
+	Kind:    "inconsistent_state",
+	Err:     utils.ErrUpstreamUnavailable,
+}
+
+// rangeCheckedProvider cross-checks the state of every answer against the
+// CEP range table, and fills it in when the provider left it empty together
+// with the full state name and region.
+type rangeCheckedProvider struct {
+	external.CepProvider
+}
+
+func (p rangeCheckedProvider) Lookup(ctx context.Context, cep string) (external.Address, error) {
+	address, err := p.CepProvider.Lookup(ctx, cep)
+	if err != nil {
+		return address, err
+	}
+	r, ok := utils.RangeOfCep(cep)
+	if !ok {
+		return address, nil
+	}
+	state := strings.ToUpper(strings.TrimSpace(address.State))
+	if state != "" && state != r.UF {
+		return external.Address{}, inconsistentStateError.WithMessage(fmt.Sprintf("provider returned state %s for cep %s, which belongs to %s", state, cep, r.UF))
+	}
+	if state == "" {
+		address.State = r.UF
+	}
+	if address.StateName == "" {
+		address.StateName = r.State
+	}
+	if address.Region == "" {
+		address.Region = r.Region
+	}
+	return address, nil
+}
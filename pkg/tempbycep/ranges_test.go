@@ -0,0 +1,44 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func TestLookupAddressRejectsCepOutsideRanges(t *testing.T) {
+	stub := &countingProvider{stubProvider: stubProvider{name: "stub", addr: external.Address{Cep: "00000000"}}}
+	svc := New(WithRegistry(external.NewRegistry(stub)))
+
+	if _, err := svc.LookupAddress(context.Background(), "00000-000"); !errors.Is(err, utils.ErrInvalidCep) {
+		t.Errorf("LookupAddress() of a CEP of no state returned %v", err)
+	}
+	if stub.calls != 0 {
+		t.Errorf("the provider was called %d times for a CEP of no state", stub.calls)
+	}
+}
+
+func TestLookupAddressChecksState(t *testing.T) {
+	wrong := &stubProvider{name: "wrong", addr: external.Address{Cep: "20541155", State: "SP", City: "São Paulo"}}
+	empty := &stubProvider{name: "empty", addr: external.Address{Cep: "20541155", City: "Rio de Janeiro"}}
+
+	// merge waits for every provider, so the wrong answer has been recorded
+	// once the lookup returns. It is discarded and the state is inferred.
+	svc := New(WithRegistry(external.NewRegistry(wrong, empty)), WithStrategy(StrategyMerge))
+	address, err := svc.LookupAddress(context.Background(), "20541155")
+	if err != nil || address.City != "Rio de Janeiro" || address.State != "RJ" || address.Source != "empty" || len(address.Conflicts) != 0 {
+		t.Errorf("LookupAddress() = %+v, %v", address, err)
+	}
+	if status := svc.health.status("wrong"); status.Failures != 1 {
+		t.Errorf("a wrong state was not counted as a failure: %+v", status)
+	}
+
+	svc = New(WithRegistry(external.NewRegistry(wrong)))
+	_, err = svc.LookupAddress(context.Background(), "20541155")
+	if !errors.Is(err, inconsistentStateError) {
+		t.Errorf("LookupAddress() with only a wrong state returned %v", err)
+	}
+}
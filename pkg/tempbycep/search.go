@@ -0,0 +1,163 @@
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const (
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 50
+)
+
+// noSearchProvidersError is returned when no registered provider has CapSearch.
+var noSearchProvidersError = utils.HttpError{Code: http.StatusServiceUnavailable, Message: "no address search providers registered", Kind: "no_providers", Err: utils.ErrUpstreamUnavailable}
+
+// SearchPage is one page of the ranked results of an address search.
+type SearchPage struct {
+	Query    external.SearchQuery `json:"query"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+	Total    int                  `json:"total"`
+	Results  []external.Address   `json:"results"`
+}
+
+// SearchAddresses lists the CEPs of a street, best matches first. page
+// starts at 1; a pageSize of zero or less means DefaultSearchPageSize and it
+// is capped at MaxSearchPageSize. The search providers are tried in registry
+// order until one answers.
+func (s *Service) SearchAddresses(ctx context.Context, q external.SearchQuery, page, pageSize int) (SearchPage, error) {
+	q.State = strings.ToUpper(strings.TrimSpace(q.State))
+	q.City = strings.TrimSpace(q.City)
+	q.Street = strings.TrimSpace(q.Street)
+	if err := q.Validate(); err != nil {
+		return SearchPage{}, err
+	}
+	if !utils.IsUF(q.State) {
+		return SearchPage{}, utils.InvalidSearchError.WithMessage("unknown uf " + q.State)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultSearchPageSize
+	}
+	pageSize = min(pageSize, MaxSearchPageSize)
+
+	addresses, err := s.searchProviders(ctx, q)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	addresses = rankAddresses(q, addresses)
+
+	result := SearchPage{Query: q, Page: page, PageSize: pageSize, Total: len(addresses), Results: []external.Address{}}
+	if start := (page - 1) * pageSize; start < len(addresses) {
+		result.Results = addresses[start:min(start+pageSize, len(addresses))]
+	}
+	return result, nil
+}
+
+// searchProviders asks the search providers in registry order. Searches go
+// through the same circuit breaker and health tracking as lookups, so a
+// provider that is down is skipped for both.
+func (s *Service) searchProviders(ctx context.Context, q external.SearchQuery) ([]external.Address, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.lookupTimeout)
+	defer cancel()
+
+	var searchers int
+	aggregate := &external.AggregateError{}
+	for _, p := range s.registry.Providers() {
+		searcher, ok := p.(external.AddressSearcher)
+		if !ok || !p.Capabilities().Has(external.CapSearch) {
+			continue
+		}
+		searchers++
+		if !s.health.allow(p.Name()) {
+			continue
+		}
+		start := time.Now()
+		addresses, err := searcher.Search(ctx, q)
+		s.health.record(p.Name(), time.Since(start), err)
+		if err == nil {
+			return addresses, nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, utils.TimeoutError.WithMessage("Timeout Reached, no API returned in time")
+		}
+		aggregate.Errors = append(aggregate.Errors, &external.ProviderError{Provider: p.Name(), Err: err})
+	}
+	switch {
+	case searchers == 0:
+		return nil, noSearchProvidersError
+	case len(aggregate.Errors) == 0:
+		return nil, circuitOpenError
+	}
+	return nil, aggregate
+}
+
+// rankAddresses drops duplicated CEPs and addresses of other states, then
+// sorts the rest by how well their street matches the query, ignoring
+// accents, case and spacing. Ties are sorted by street and CEP.
+func rankAddresses(q external.SearchQuery, addresses []external.Address) []external.Address {
+	want := normalizeText(q.Street)
+	seen := make(map[string]bool, len(addresses))
+	type ranked struct {
+		address external.Address
+		street  string
+		score   int
+	}
+	var out []ranked
+	for _, a := range addresses {
+		cep := strings.ReplaceAll(a.Cep, "-", "")
+		if seen[cep] || (a.State != "" && !strings.EqualFold(a.State, q.State)) {
+			continue
+		}
+		seen[cep] = true
+		street := normalizeText(a.Street)
+		out = append(out, ranked{address: a, street: street, score: matchScore(street, want)})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		if out[i].street != out[j].street {
+			return out[i].street < out[j].street
+		}
+		return out[i].address.Cep < out[j].address.Cep
+	})
+
+	result := make([]external.Address, len(out))
+	for i, r := range out {
+		result[i] = r.address
+	}
+	return result
+}
+
+// matchScore rates how well street matches want, both normalized: the same
+// name, the same name after the kind of street (e.g. "rua"), a prefix, a
+// substring, every word somewhere, or nothing of the above.
+func matchScore(street, want string) int {
+	switch {
+	case street == want:
+		return 5
+	case strings.HasSuffix(street, " "+want) && !strings.Contains(strings.TrimSuffix(street, " "+want), " "):
+		return 4
+	case strings.HasPrefix(street, want):
+		return 3
+	case strings.Contains(street, want):
+		return 2
+	}
+	for _, word := range strings.Fields(want) {
+		if !strings.Contains(street, word) {
+			return 0
+		}
+	}
+	return 1
+}
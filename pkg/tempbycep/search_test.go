@@ -0,0 +1,145 @@
+package tempbycep
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+// searchProvider is a stubProvider that also answers address searches.
+type searchProvider struct {
+	stubProvider
+	results []external.Address
+	query   external.SearchQuery
+}
+
+func (s *searchProvider) Search(ctx context.Context, q external.SearchQuery) ([]external.Address, error) {
+	s.query = q
+	return s.results, s.err
+}
+
+func (s *searchProvider) Capabilities() external.Capabilities {
+	return external.CapLookup | external.CapSearch
+}
+
+func TestSearchAddresses(t *testing.T) {
+	searcher := &searchProvider{stubProvider: stubProvider{name: "search"}, results: []external.Address{
+		{Cep: "20270-002", State: "RJ", Street: "Travessa Paulo de Frontin"},
+		{Cep: "20260-010", State: "RJ", Street: "Avenida Paulo de Frontin"},
+		{Cep: "20260-010", State: "RJ", Street: "Avenida Paulo de Frontin"},
+		{Cep: "20260-001", State: "RJ", Street: "Rua Paulo de Frontin Filho"},
+		{Cep: "01001-000", State: "SP", Street: "Avenida Paulo de Frontin"},
+		{Cep: "20260-020", State: "RJ", Street: "Rua do Paulo"},
+	}}
+	lookupOnly := &stubProvider{name: "lookup"}
+	svc := New(WithRegistry(external.NewRegistry(lookupOnly, searcher)))
+
+	result, err := svc.SearchAddresses(context.Background(), external.SearchQuery{State: "rj", City: "Rio de Janeiro", Street: " PAULO DE FRONTÍN "}, 1, 2)
+	if err != nil {
+		t.Fatalf("SearchAddresses() returned an error: %v", err)
+	}
+	if searcher.query.State != "RJ" || searcher.query.Street != "PAULO DE FRONTÍN" {
+		t.Errorf("the provider was asked for %+v", searcher.query)
+	}
+	// duplicates and other states are dropped, accents and case are ignored
+	if result.Total != 4 || len(result.Results) != 2 || result.PageSize != 2 {
+		t.Fatalf("SearchAddresses() = %+v", result)
+	}
+	if result.Results[0].Cep != "20260-010" || result.Results[1].Cep != "20270-002" {
+		t.Errorf("first page ranked %v, %v", result.Results[0], result.Results[1])
+	}
+
+	result, _ = svc.SearchAddresses(context.Background(), external.SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Paulo de Frontin"}, 2, 2)
+	if len(result.Results) != 2 || result.Results[0].Cep != "20260-001" || result.Results[1].Cep != "20260-020" {
+		t.Errorf("second page = %+v", result.Results)
+	}
+	result, _ = svc.SearchAddresses(context.Background(), external.SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Paulo de Frontin"}, 3, 2)
+	if result.Results == nil || len(result.Results) != 0 {
+		t.Errorf("a page past the end = %+v", result.Results)
+	}
+}
+
+func TestSearchAddressesErrors(t *testing.T) {
+	svc := New(WithRegistry(external.NewRegistry(&stubProvider{name: "lookup"})))
+	for _, q := range []external.SearchQuery{
+		{State: "XX", City: "Rio de Janeiro", Street: "Paulo de Frontin"},
+		{State: "RJ", City: "Ri", Street: "Paulo de Frontin"},
+		{State: "RJ", City: "Rio de Janeiro", Street: ""},
+	} {
+		if _, err := svc.SearchAddresses(context.Background(), q, 1, 0); !errors.Is(err, utils.ErrInvalidQuery) {
+			t.Errorf("SearchAddresses(%+v) returned %v", q, err)
+		}
+	}
+
+	q := external.SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Paulo de Frontin"}
+	if _, err := svc.SearchAddresses(context.Background(), q, 1, 0); !errors.Is(err, noSearchProvidersError) {
+		t.Errorf("SearchAddresses() without search providers returned %v", err)
+	}
+
+	down := &searchProvider{stubProvider: stubProvider{name: "down", err: utils.UpstreamError}}
+	svc = New(WithRegistry(external.NewRegistry(down)))
+	_, err := svc.SearchAddresses(context.Background(), q, 1, 0)
+	var aggregate *external.AggregateError
+	if !errors.As(err, &aggregate) || !errors.Is(err, utils.ErrUpstreamUnavailable) {
+		t.Errorf("SearchAddresses() with a failing provider returned %v", err)
+	}
+}
+
+func TestSearchAddressesCircuitBreaker(t *testing.T) {
+	down := &searchProvider{stubProvider: stubProvider{name: "down", err: utils.UpstreamError}}
+	svc := New(WithRegistry(external.NewRegistry(down)), WithCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}))
+	q := external.SearchQuery{State: "RJ", City: "Rio de Janeiro", Street: "Paulo de Frontin"}
+
+	if _, err := svc.SearchAddresses(context.Background(), q, 1, 0); !errors.Is(err, utils.ErrUpstreamUnavailable) {
+		t.Fatalf("SearchAddresses() with a failing provider returned %v", err)
+	}
+	if status := svc.ProviderStatus()[0]; status.State != CircuitOpen || status.Failures != 1 {
+		t.Fatalf("a failed search left %+v", status)
+	}
+	down.query = external.SearchQuery{}
+	if _, err := svc.SearchAddresses(context.Background(), q, 1, 0); !errors.Is(err, circuitOpenError) {
+		t.Errorf("SearchAddresses() with an open circuit returned %v", err)
+	}
+	if down.query.State != "" {
+		t.Error("a provider with an open circuit was searched")
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	searcher := &searchProvider{stubProvider: stubProvider{name: "search"}, results: []external.Address{
+		{Cep: "20260-010", State: "RJ", City: "Rio de Janeiro", Street: "Avenida Paulo de Frontin"},
+	}}
+	svc := New(WithRegistry(external.NewRegistry(searcher)))
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/cep?uf=RJ&city=Rio+de+Janeiro&street=Paulo+de+Frontin&page_size=10", nil))
+	var page SearchPage
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /search/cep = %d, %v", rec.Code, err)
+	}
+	if page.Total != 1 || page.PageSize != 10 || page.Results[0].Cep != "20260-010" || searcher.query.City != "Rio de Janeiro" {
+		t.Errorf("GET /search/cep = %+v", page)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/cep?uf=RJ&city=Rio&street=Paulo&page=zero", nil))
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("GET /search/cep with a bad page = %d %s", rec.Code, rec.Body)
+	}
+
+	for i := 0; i < 10; i++ {
+		rec = httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search/cep?uf=RJ&city=Rio&street=Paulo&page=0&page_size=0", nil))
+		if !strings.Contains(rec.Body.String(), "page must be a positive number") {
+			t.Fatalf("GET /search/cep with a bad page and page_size = %s", rec.Body)
+		}
+	}
+}
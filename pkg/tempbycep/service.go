@@ -0,0 +1,283 @@
+// Package tempbycep resolves Brazilian CEPs into addresses and current
+// temperatures. It is the library behind the HTTP service and can be embedded
+// directly by other Go programs.
+package tempbycep
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cache"
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+const defaultLookupTimeout = time.Second * 1
+
+// Service looks up addresses and temperatures by CEP. Build it with New.
+type Service struct {
+	client        *external.Client
+	registry      *external.Registry
+	providers     []string
+	localIndex    *cepindex.Index
+	lookupTimeout time.Duration
+	lang          string
+	strategy      Strategy
+	hedge         HedgeConfig
+
+	addressCacheConfig CacheConfig
+	addresses          *cache.LRU[addressEntry]
+	weatherCacheConfig CacheConfig
+	weather            *cache.LRU[external.CurrentModel]
+	store              cache.Store
+	health             *healthTracker
+	geocodes           *cache.LRU[*external.Coordinates]
+
+	// concurrent misses for the same CEP or location share one upstream call
+	addressFlight cache.FlightGroup[external.Address]
+	weatherFlight cache.FlightGroup[external.CurrentModel]
+	geocodeFlight cache.FlightGroup[*external.Coordinates]
+}
+
+type Option func(*Service)
+
+// WithClient sets the client used for WeatherAPI and for the default providers.
+func WithClient(c *external.Client) Option {
+	return func(s *Service) {
+		s.client = c
+	}
+}
+
+// WithRegistry replaces the default BrasilAPI + ViaCEP providers.
+func WithRegistry(r *external.Registry) Option {
+	return func(s *Service) {
+		s.registry = r
+	}
+}
+
+// WithProviders builds the registry from the named built-in providers, in
+// order of preference, backed by the service client. Unknown names are
+// skipped. WithRegistry takes precedence.
+func WithProviders(names ...string) Option {
+	return func(s *Service) {
+		s.providers = names
+	}
+}
+
+// WithLocalIndex adds a LocalCepProvider backed by ix. It is the first
+// provider unless WithProviders places "local" elsewhere.
+func WithLocalIndex(ix *cepindex.Index) Option {
+	return func(s *Service) {
+		s.localIndex = ix
+	}
+}
+
+// WithLookupTimeout bounds how long LookupAddress waits for the providers.
+func WithLookupTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.lookupTimeout = d
+	}
+}
+
+// WithLanguage sets the language of the weather condition texts.
+func WithLanguage(lang string) Option {
+	return func(s *Service) {
+		s.lang = lang
+	}
+}
+
+func New(opts ...Option) *Service {
+	s := &Service{
+		client:        external.DefaultClient(),
+		lookupTimeout: defaultLookupTimeout,
+		lang:          "pt",
+		strategy:      StrategyHedge,
+		hedge:         DefaultHedge,
+
+		addressCacheConfig: DefaultAddressCache,
+		weatherCacheConfig: DefaultWeatherCache,
+		health:             newHealthTracker(),
+		geocodes:           cache.NewLRU[*external.Coordinates](geocodeCacheSize),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.registry == nil {
+		s.registry = s.buildRegistry()
+	}
+	if s.addressCacheConfig.Size > 0 {
+		s.addresses = cache.NewLRU[addressEntry](s.addressCacheConfig.Size)
+	}
+	if s.weatherCacheConfig.Size > 0 {
+		s.weather = cache.NewLRU[external.CurrentModel](s.weatherCacheConfig.Size)
+	}
+	return s
+}
+
+// buildRegistry builds the registry from the provider names, the local index
+// and the client.
+func (s *Service) buildRegistry() *external.Registry {
+	local := external.LocalCepProvider{Index: s.localIndex}
+	if len(s.providers) == 0 {
+		r := external.NewRegistry(
+			external.BrasilApiProvider{Client: s.client},
+			external.ViaCepProvider{Client: s.client},
+		)
+		if s.localIndex != nil {
+			r.Register(local)
+			r.Reorder(local.Name())
+		}
+		return r
+	}
+
+	r := external.NewRegistry()
+	for _, name := range s.providers {
+		if strings.EqualFold(strings.TrimSpace(name), local.Name()) {
+			if s.localIndex == nil {
+				log.Printf("cep provider %q needs a local index, skipping it", name)
+				continue
+			}
+			r.Register(local)
+			continue
+		}
+		p, err := external.NewBuiltinProvider(name, s.client)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		r.Register(p)
+	}
+	return r
+}
+
+// Registry returns the providers LookupAddress fans out to, so callers can
+// register their own.
+func (s *Service) Registry() *external.Registry {
+	return s.registry
+}
+
+// Temperature is the current temperature at the address of a CEP.
+type Temperature struct {
+	TempC float32 `json:"temp_c"`
+	TempF float32 `json:"temp_f"`
+	TempK float32 `json:"temp_k"`
+	// Location is where the weather was read: the coordinates of the address
+	// rounded to two decimals, as WeatherAPI was asked about them.
+	Location external.Coordinates `json:"location"`
+}
+
+// LookupAddress resolves cep through the registered providers, called as the
+// Strategy says. The lookup is bounded by the lookup timeout and is also
+// cancelled together with ctx. Addresses and definitive not found answers are
+// cached, and concurrent lookups of the same CEP share one round of provider
+// calls. CEPs outside every state range are rejected without calling any
+// provider, and answers whose state does not own the CEP are discarded.
+func (s *Service) LookupAddress(ctx context.Context, cep string) (external.Address, error) {
+	// remove separator if exists
+	cep = strings.ReplaceAll(cep, "-", "")
+	// CEPs of no state are rejected before any cache or provider call
+	if err := utils.ValidateCep(cep); err != nil {
+		return external.Address{}, utils.InvalidZipError.WithMessage(err.Error())
+	}
+	if entry, ok := s.cachedAddress(cep); ok {
+		return entry.address, entry.err
+	}
+
+	return s.addressFlight.Do(ctx, cep, func(ctx context.Context) (external.Address, error) {
+		address, err := s.lookupProviders(ctx, cep)
+		s.storeAddress(cep, address, err)
+		return address, err
+	})
+}
+
+func (s *Service) lookupProviders(ctx context.Context, cep string) (external.Address, error) {
+	if len(s.registry.Providers()) == 0 {
+		return external.Address{}, utils.HttpError{Code: http.StatusServiceUnavailable, Message: "no cep providers registered", Kind: "no_providers", Err: utils.ErrUpstreamUnavailable}
+	}
+	providers := s.availableProviders()
+	if len(providers) == 0 {
+		return external.Address{}, circuitOpenError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.lookupTimeout)
+	defer cancel()
+
+	res, err := s.fanOut(ctx, providers, cep)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return external.Address{}, utils.TimeoutError.WithMessage("Timeout Reached, no API returned in time. CEP: " + cep)
+	}
+	return res, err
+}
+
+// CurrentTemperature resolves cep and returns the current temperature at its
+// coordinates, see geocode. Addresses that cannot be geocoded fail with
+// unresolvedLocationError. The returned location is the one WeatherAPI was
+// asked about, rounded as in weatherQuery.
+func (s *Service) CurrentTemperature(ctx context.Context, cep string) (Temperature, error) {
+	c, err := s.LookupAddress(ctx, cep)
+	if err != nil {
+		return Temperature{}, err
+	}
+
+	location, err := s.geocode(ctx, c)
+	if err != nil {
+		return Temperature{}, err
+	}
+	queried := roundLocation(*location)
+
+	temp, err := s.currentWeather(ctx, weatherQuery(queried))
+	if err != nil {
+		return Temperature{}, err
+	}
+
+	return Temperature{
+		TempC:    temp.Current.TempC,
+		TempF:    temp.Current.TempF,
+		TempK:    temp.Current.TempC + 273,
+		Location: queried,
+	}, nil
+}
+
+// roundLocation rounds location to two decimals, about a kilometre, finer
+// than the weather grid, so close CEPs share a cached reading.
+func roundLocation(location external.Coordinates) external.Coordinates {
+	return external.Coordinates{
+		Latitude:  math.Round(location.Latitude*100) / 100,
+		Longitude: math.Round(location.Longitude*100) / 100,
+	}
+}
+
+// weatherQuery is the "lat,lon" WeatherAPI query of location.
+func weatherQuery(location external.Coordinates) string {
+	return strconv.FormatFloat(location.Latitude, 'f', 2, 64) + "," + strconv.FormatFloat(location.Longitude, 'f', 2, 64)
+}
+
+// currentWeather asks WeatherAPI for the weather at query, going through the
+// weather cache. Concurrent misses for the same location share one request.
+func (s *Service) currentWeather(ctx context.Context, query string) (external.CurrentModel, error) {
+	key := weatherKey(query, s.lang)
+	if current, ok := s.cachedWeather(key); ok {
+		return current, nil
+	}
+
+	return s.weatherFlight.Do(ctx, key, func(ctx context.Context) (external.CurrentModel, error) {
+		current, err := s.client.CurrentWeather(ctx, query, s.lang)
+		if err != nil {
+			// network failures reaching WeatherAPI are upstream failures too
+			var detailer utils.ProblemDetailer
+			if !errors.As(err, &detailer) {
+				err = &external.ProviderError{Provider: "weatherAPI", Err: err}
+			}
+			return external.CurrentModel{}, err
+		}
+		s.storeWeather(key, current)
+		return current, nil
+	})
+}
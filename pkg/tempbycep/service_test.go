@@ -0,0 +1,542 @@
+package tempbycep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JonecoBoy/tempByCep/pkg/cache"
+	"github.com/JonecoBoy/tempByCep/pkg/cepindex"
+	"github.com/JonecoBoy/tempByCep/pkg/external"
+	"github.com/JonecoBoy/tempByCep/pkg/utils"
+)
+
+func TestLookupAddress(t *testing.T) {
+	cep := "20541-155"
+	result, err := New().LookupAddress(context.Background(), cep)
+	if err != nil {
+		t.Errorf("LookupAddress() returned an error: %v", err)
+	}
+
+	if strings.ReplaceAll(result.Cep, "-", "") != strings.ReplaceAll(cep, "-", "") {
+		t.Errorf("LookupAddress() returned an invalid CEP: %v", result.Cep)
+	}
+
+	fields := []string{
+		"Cep", "State", "City", "Neighborhood", "Street", "Source",
+	}
+
+	concurrencyCepVal := reflect.ValueOf(result)
+	for _, field := range fields {
+		val := concurrencyCepVal.FieldByName(field)
+		if !val.IsValid() {
+			t.Errorf("LookupAddress() did not return a Marine struct with the field %s", field)
+		}
+	}
+}
+
+func TestLookupAddressZipNotFound(t *testing.T) {
+	cep := "90541155"
+	_, err := New().LookupAddress(context.Background(), cep)
+	if err == nil {
+		t.Fatalf("LookupAddress() returned a value instead of an err: %v", err)
+	}
+	if !errors.Is(err, utils.ErrNotFound) {
+		t.Errorf("LookupAddress() did not return an " + utils.ZipNotFoundError.Error() + " error")
+	}
+
+}
+
+func TestLookupAddressInvalidZipFormat(t *testing.T) {
+	cep := "905411551"
+	_, err := New().LookupAddress(context.Background(), cep)
+	if err == nil {
+		t.Fatalf("LookupAddress() returned a value instead of an err: %v", err)
+	}
+	if !errors.Is(err, utils.ErrInvalidCep) {
+		t.Errorf("LookupAddress() did not return an " + utils.InvalidZipError.Error() + " error")
+	}
+}
+
+func TestGetTempByCep(t *testing.T) {
+	if !external.DefaultClient().HasWeatherApiKey() {
+		t.Skipf("set %s to run the WeatherAPI tests", external.EnvWeatherApiKey)
+	}
+	cep := "25900-028"
+	result, err := New().LookupAddress(context.Background(), cep)
+	if err != nil {
+		t.Errorf("LookupAddress() returned an error: %v", err)
+	}
+
+	if strings.ReplaceAll(result.Cep, "-", "") != strings.ReplaceAll(cep, "-", "") {
+		t.Errorf("LookupAddress() returned an invalid CEP: %v", result.Cep)
+	}
+
+	fields := []string{
+		"Cep", "State", "City", "Neighborhood", "Street", "Source",
+	}
+
+	viaCepVal := reflect.ValueOf(result)
+	for _, field := range fields {
+		val := viaCepVal.FieldByName(field)
+		if !val.IsValid() {
+			t.Errorf("LookupAddress() did not return a Marine struct with the field %s", field)
+		}
+	}
+
+	query := strings.Join([]string{utils.RemoveAccents(result.City), utils.RemoveAccents(result.State), "brazil"}, "-")
+	lang := "pt"
+
+	result2, err := external.CurrentWeather(context.Background(), query, lang)
+	if err != nil {
+		t.Errorf("Current() returned an error: %v", err)
+	}
+
+	if *result2.Current == (external.Current{}) {
+		t.Errorf("Current() returned an empty Current struct")
+	}
+
+	if *result2.Current.Condition == (external.Condition{}) {
+		t.Errorf("Current() returned an empty Current struct")
+	}
+
+	// testando se os principais campos estão de volta
+	fields2 := []string{
+		"WindMph", "WindKph", "WindDegree", "WindDir", "PressureMb", "PressureIn", "PrecipMm", "PrecipIn",
+		"Humidity", "Cloud", "FeelslikeC", "FeelslikeF", "TempC", "TempF", "IsDay", "WindMph",
+		"WindKph", "WindDegree", "WindDir", "Humidity", "Uv", "GustMph", "GustKph",
+	}
+
+	currentVal := reflect.ValueOf(*result2.Current) // Dereference the pointer here
+	for _, field := range fields2 {
+		val := currentVal.FieldByName(field)
+		if !val.IsValid() {
+			t.Errorf("Current() did not return a Current struct with the field %s", field)
+		}
+	}
+}
+
+type stubProvider struct {
+	name  string
+	delay time.Duration
+	addr  external.Address
+	err   error
+	// cancelled is closed when the lookup observes ctx cancellation
+	cancelled chan struct{}
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) Lookup(ctx context.Context, cep string) (external.Address, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.addr, s.err
+	case <-ctx.Done():
+		if s.cancelled != nil {
+			close(s.cancelled)
+		}
+		return external.Address{}, ctx.Err()
+	}
+}
+
+func (s *stubProvider) Capabilities() external.Capabilities { return external.CapLookup }
+
+func TestRaceProvidersFailureDoesNotHideSuccess(t *testing.T) {
+	fastFailure := &stubProvider{name: "fast", err: errors.New("unkown error")}
+	slowSuccess := &stubProvider{name: "slow", delay: 20 * time.Millisecond, addr: external.Address{Cep: "20541155", Source: "slow"}}
+
+	result, err := raceProviders(context.Background(), []external.CepProvider{fastFailure, slowSuccess}, "20541155")
+	if err != nil {
+		t.Fatalf("raceProviders() returned an error: %v", err)
+	}
+	if result.Source != "slow" {
+		t.Errorf("raceProviders() returned address from %q, expected slow", result.Source)
+	}
+}
+
+func TestRaceProvidersCancelsLosers(t *testing.T) {
+	winner := &stubProvider{name: "winner", addr: external.Address{Source: "winner"}}
+	loser := &stubProvider{name: "loser", delay: time.Minute, cancelled: make(chan struct{})}
+
+	_, err := raceProviders(context.Background(), []external.CepProvider{winner, loser}, "20541155")
+	if err != nil {
+		t.Fatalf("raceProviders() returned an error: %v", err)
+	}
+
+	select {
+	case <-loser.cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("raceProviders() did not cancel the losing provider")
+	}
+}
+
+func TestRaceProvidersAggregatesErrors(t *testing.T) {
+	a := &stubProvider{name: "a", err: utils.ZipNotFoundError}
+	b := &stubProvider{name: "b", err: errors.New("unkown error")}
+
+	_, err := raceProviders(context.Background(), []external.CepProvider{a, b}, "90541155")
+	var aggregate *external.AggregateError
+	if !errors.As(err, &aggregate) {
+		t.Fatalf("raceProviders() returned %T, expected *external.AggregateError", err)
+	}
+	if len(aggregate.Errors) != 2 {
+		t.Errorf("AggregateError has %d errors, expected 2", len(aggregate.Errors))
+	}
+	if !errors.Is(err, utils.ZipNotFoundError) {
+		t.Errorf("AggregateError does not wrap %v", utils.ZipNotFoundError)
+	}
+
+	// when every provider agrees the message is not repeated
+	_, err = raceProviders(context.Background(), []external.CepProvider{a, &stubProvider{name: "c", err: utils.ZipNotFoundError}}, "90541155")
+	if !errors.Is(err, utils.ErrNotFound) {
+		t.Errorf("raceProviders() returned %q, expected %q", err.Error(), utils.ZipNotFoundError.Error())
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	err := &external.AggregateError{Cep: "90541155", Errors: []error{
+		&external.ProviderError{Provider: "brasilAPI", Err: errors.New("unkown error")},
+		&external.ProviderError{Provider: "ViaCEP", Err: utils.ZipNotFoundError},
+	}}
+
+	rec := httptest.NewRecorder()
+	writeProblem(rec, httptest.NewRequest("GET", "/cep/90541155", nil), err, "90541155")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("writeProblem() status = %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("writeProblem() Content-Type = %q", ct)
+	}
+
+	var p utils.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("writeProblem() wrote invalid json: %v", err)
+	}
+	if p.Code != "cep_not_found" || p.Cep != "90541155" || p.Provider != "brasilAPI,ViaCEP" || p.Instance != "/cep/90541155" {
+		t.Errorf("writeProblem() wrote unexpected problem: %+v", p)
+	}
+
+	rec = httptest.NewRecorder()
+	writeProblem(rec, httptest.NewRequest("GET", "/temp/x", nil), errors.New("boom"), "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("writeProblem() status for untyped error = %d, expected 500", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	writeProblem(rec, httptest.NewRequest("GET", "/temp/20541155", nil), &external.RateLimitError{Provider: "weatherAPI", Wait: 1500 * time.Millisecond}, "20541155")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("writeProblem() for a rate limit = %d with Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestServiceCurrentTemperature(t *testing.T) {
+	var query, search string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "search.json") {
+			search = r.URL.Query().Get("q")
+			// a Magé in another state comes first
+			w.Write([]byte(`[{"name":"Mage","region":"Bahia","country":"Brazil","lat":-12.1,"lon":-38.9},{"name":"Mage","region":"Rio de Janeiro","country":"Brazil","lat":-22.66,"lon":-43.02}]`))
+			return
+		}
+		query = r.URL.Query().Get("q")
+		w.Write([]byte(`{"current":{"temp_c":21.5,"temp_f":70.7}}`))
+	}))
+	defer srv.Close()
+
+	client, err := external.NewClient(external.WithWeatherApiURL(srv.URL), external.WithWeatherApiKeys("key"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	registry := external.NewRegistry(&stubProvider{name: "stub", addr: external.Address{Cep: "25900028", City: "Magé", State: "RJ"}})
+	svc := New(WithClient(client), WithRegistry(registry))
+
+	temp, err := svc.CurrentTemperature(context.Background(), "25900-028")
+	if err != nil {
+		t.Fatalf("CurrentTemperature() returned an error: %v", err)
+	}
+	if search != "Mage-Rio de Janeiro-brazil" || query != "-22.66,-43.02" {
+		t.Errorf("CurrentTemperature() searched %q and queried %q", search, query)
+	}
+	if temp.TempC != 21.5 || temp.TempK != 294.5 || temp.Location.Latitude != -22.66 || temp.Location.Longitude != -43.02 {
+		t.Errorf("CurrentTemperature() returned %+v", temp)
+	}
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/temp/25900-028", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"temp_c":21.5`) || !strings.Contains(rec.Body.String(), `"location":{"latitude":-22.66,"longitude":-43.02}`) {
+		t.Errorf("GET /temp/ returned %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCurrentTemperatureGeocoding(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "search.json") {
+			w.Write([]byte(`[{"name":"Bom Jesus","region":"Piaui","country":"Brazil","lat":-9.07,"lon":-44.36}]`))
+			return
+		}
+		queries = append(queries, r.URL.Query().Get("q"))
+		w.Write([]byte(`{"current":{"temp_c":25}}`))
+	}))
+	defer srv.Close()
+	client, err := external.NewClient(external.WithWeatherApiURL(srv.URL), external.WithWeatherApiKeys("key"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+
+	// coordinates sent by the provider are used, reported as they were queried
+	located := &stubProvider{name: "located", addr: external.Address{City: "Rio de Janeiro", State: "RJ", Location: &external.Coordinates{Latitude: -22.92571, Longitude: -43.24823}}}
+	temp, err := New(WithClient(client), WithRegistry(external.NewRegistry(located))).CurrentTemperature(context.Background(), "20541155")
+	if err != nil || temp.Location.Latitude != -22.93 || temp.Location.Longitude != -43.25 {
+		t.Errorf("CurrentTemperature() with provider coordinates = %+v, %v", temp, err)
+	}
+
+	// a search result of another state is not used, and neither is the city name
+	ambiguous := &stubProvider{name: "ambiguous", addr: external.Address{City: "Bom Jesus", State: "RS"}}
+	svc := New(WithClient(client), WithRegistry(external.NewRegistry(ambiguous)))
+	_, err = svc.CurrentTemperature(context.Background(), "95290000")
+	if !errors.Is(err, unresolvedLocationError) || !errors.Is(err, utils.ErrUpstreamUnavailable) {
+		t.Errorf("CurrentTemperature() without a match in the state returned %v", err)
+	}
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/temp/95290000", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), `"code":"unresolved_location"`) {
+		t.Errorf("GET /temp/ without a match in the state returned %d %s", rec.Code, rec.Body)
+	}
+
+	if strings.Join(queries, " ") != "-22.93,-43.25" {
+		t.Errorf("WeatherAPI queried %q", queries)
+	}
+}
+
+func TestCepHandlerFieldMask(t *testing.T) {
+	stub := &countingProvider{stubProvider: stubProvider{name: "stub", addr: external.Address{Cep: "20541155", City: "Rio de Janeiro", Ibge: "3304557", Location: &external.Coordinates{Latitude: -22.92, Longitude: -43.25}}}}
+	svc := New(WithRegistry(external.NewRegistry(stub)))
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/cep/20541155", nil))
+	var full map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &full)
+	// the state name and region come from the range table
+	if full["state"] != "RJ" || full["state_name"] != "Rio de Janeiro" || full["region"] != "Sudeste" || full["ibge"] != "3304557" || full["location"] == nil {
+		t.Errorf("GET /cep/ returned %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/cep/20541155?fields=cep,%20ibge,location,complement", nil))
+	if rec.Body.String() != `{"cep":"20541155","ibge":"3304557","location":{"latitude":-22.92,"longitude":-43.25}}` {
+		t.Errorf("GET /cep/ with a field mask returned %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/cep/20541155?fields=cep,latitude", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"invalid_fields"`) {
+		t.Errorf("GET /cep/ with an unknown field returned %d %s", rec.Code, rec.Body)
+	}
+	if stub.calls != 1 {
+		t.Errorf("provider called %d times, expected 1", stub.calls)
+	}
+}
+
+type countingProvider struct {
+	stubProvider
+	calls int32
+}
+
+func (c *countingProvider) Lookup(ctx context.Context, cep string) (external.Address, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return c.stubProvider.Lookup(ctx, cep)
+}
+
+func TestWithProviders(t *testing.T) {
+	svc := New(WithProviders("postmon", "ViaCEP", "correios"))
+	var names []string
+	for _, p := range svc.Registry().Providers() {
+		names = append(names, p.Name())
+	}
+	if strings.Join(names, ",") != "Postmon,ViaCEP" {
+		t.Errorf("registry holds %v, expected Postmon,ViaCEP", names)
+	}
+
+	// the local index goes first unless placed explicitly
+	var buf bytes.Buffer
+	cepindex.NewBuilder().WriteTo(&buf)
+	ix, err := cepindex.Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() returned an error: %v", err)
+	}
+	svc = New(WithLocalIndex(ix))
+	if providers := svc.Registry().Providers(); len(providers) != 3 || providers[0].Name() != "local" {
+		t.Errorf("registry with a local index = %v", providers)
+	}
+	svc = New(WithLocalIndex(ix), WithProviders("ViaCEP", "local"))
+	if providers := svc.Registry().Providers(); len(providers) != 2 || providers[1].Name() != "local" {
+		t.Errorf("registry with the local index as fallback = %v", providers)
+	}
+}
+
+func TestLookupAddressCache(t *testing.T) {
+	found := &countingProvider{stubProvider: stubProvider{name: "found", addr: external.Address{Cep: "20541155"}}}
+	svc := New(WithRegistry(external.NewRegistry(found)))
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.LookupAddress(context.Background(), "20541-155"); err != nil {
+			t.Fatalf("LookupAddress() returned an error: %v", err)
+		}
+	}
+	if found.calls != 1 {
+		t.Errorf("provider called %d times, expected 1", found.calls)
+	}
+
+	missing := &countingProvider{stubProvider: stubProvider{name: "missing", err: utils.ZipNotFoundError}}
+	flaky := &countingProvider{stubProvider: stubProvider{name: "flaky", err: utils.UpstreamError}}
+	svc = New(WithRegistry(external.NewRegistry(missing)))
+	for i := 0; i < 2; i++ {
+		if _, err := svc.LookupAddress(context.Background(), "90541155"); !errors.Is(err, utils.ErrNotFound) {
+			t.Fatalf("LookupAddress() returned %v, expected not found", err)
+		}
+	}
+	if missing.calls != 1 {
+		t.Errorf("not found answer was not cached, provider called %d times", missing.calls)
+	}
+
+	// not found next to an outage is not definitive
+	svc = New(WithRegistry(external.NewRegistry(&countingProvider{stubProvider: stubProvider{name: "missing", err: utils.ZipNotFoundError}}, flaky)))
+	svc.LookupAddress(context.Background(), "90541155")
+	svc.LookupAddress(context.Background(), "90541155")
+	if flaky.calls != 2 {
+		t.Errorf("mixed failure was cached, provider called %d times", flaky.calls)
+	}
+
+	stats := svc.CacheStats().Addresses
+	if stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("CacheStats() = %+v", stats)
+	}
+
+	// disabled cache
+	found.calls = 0
+	svc = New(WithRegistry(external.NewRegistry(found)), WithAddressCache(CacheConfig{}))
+	svc.LookupAddress(context.Background(), "20541155")
+	svc.LookupAddress(context.Background(), "20541155")
+	if found.calls != 2 {
+		t.Errorf("disabled cache served a lookup, provider called %d times", found.calls)
+	}
+}
+
+func TestLookupAddressCoalescesConcurrentCalls(t *testing.T) {
+	slow := &countingProvider{stubProvider: stubProvider{name: "slow", delay: 50 * time.Millisecond, addr: external.Address{Cep: "20541155"}}}
+	// without a cache only the coalescing can save provider calls
+	svc := New(WithRegistry(external.NewRegistry(slow)), WithAddressCache(CacheConfig{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if addr, err := svc.LookupAddress(context.Background(), "20541-155"); err != nil || addr.Cep != "20541155" {
+				t.Errorf("LookupAddress() = %+v, %v", addr, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls := atomic.LoadInt32(&slow.calls); calls != 1 {
+		t.Errorf("provider called %d times for concurrent lookups, expected 1", calls)
+	}
+}
+
+func TestCurrentTemperatureWeatherCache(t *testing.T) {
+	var calls, searches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "search.json") {
+			atomic.AddInt32(&searches, 1)
+			w.Write([]byte(`[{"name":"Rio de Janeiro","region":"Rio de Janeiro","country":"Brazil","lat":-22.9,"lon":-43.23}]`))
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, `{"current":{"last_updated_epoch":%d,"temp_c":30}}`, time.Now().Unix())
+	}))
+	defer srv.Close()
+
+	client, err := external.NewClient(external.WithWeatherApiURL(srv.URL), external.WithWeatherApiKeys("key"))
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	// two CEPs of the same city share one weather reading
+	registry := external.NewRegistry(&stubProvider{name: "stub", addr: external.Address{City: "Rio de Janeiro", State: "RJ"}})
+	svc := New(WithClient(client), WithRegistry(registry))
+
+	for _, cep := range []string{"20541155", "20541156", "20541155"} {
+		if _, err := svc.CurrentTemperature(context.Background(), cep); err != nil {
+			t.Fatalf("CurrentTemperature() returned an error: %v", err)
+		}
+	}
+	if calls != 1 || searches != 1 {
+		t.Errorf("WeatherAPI called %d times and searched %d times, expected 1 each", calls, searches)
+	}
+	if stats := svc.CacheStats().Weather; stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("CacheStats().Weather = %+v", stats)
+	}
+}
+
+func TestWeatherTTL(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	updated := &external.Current{LastUpdatedEpoch: int32(now.Add(-5 * time.Minute).Unix())}
+	if got := weatherTTL(updated, now, time.Hour); got != 10*time.Minute {
+		t.Errorf("weatherTTL() = %v, expected 10m until the next update", got)
+	}
+	if got := weatherTTL(updated, now, 2*time.Minute); got != 2*time.Minute {
+		t.Errorf("weatherTTL() = %v, expected the 2m cap", got)
+	}
+	stale := &external.Current{LastUpdatedEpoch: int32(now.Add(-time.Hour).Unix())}
+	if got := weatherTTL(stale, now, time.Hour); got != time.Minute {
+		t.Errorf("weatherTTL() = %v for an overdue reading, expected 1m", got)
+	}
+	if got := weatherTTL(&external.Current{}, now, 7*time.Minute); got != 7*time.Minute {
+		t.Errorf("weatherTTL() = %v without last_updated_epoch, expected the cap", got)
+	}
+}
+
+func TestLookupAddressPersistentCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store, err := cache.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() returned an error: %v", err)
+	}
+	found := &countingProvider{stubProvider: stubProvider{name: "found", addr: external.Address{Cep: "20541155", City: "Rio de Janeiro"}}}
+	missing := &countingProvider{stubProvider: stubProvider{name: "missing", err: utils.ZipNotFoundError}}
+	registry := external.NewRegistry(found)
+	New(WithRegistry(registry), WithPersistentCache(store)).LookupAddress(context.Background(), "20541155")
+	New(WithRegistry(external.NewRegistry(missing)), WithPersistentCache(store)).LookupAddress(context.Background(), "90541155")
+	store.Close()
+
+	// a new process with an empty memory cache
+	store, err = cache.OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() returned an error: %v", err)
+	}
+	defer store.Close()
+	svc := New(WithRegistry(external.NewRegistry(found, missing)), WithPersistentCache(store))
+
+	address, err := svc.LookupAddress(context.Background(), "20541155")
+	if err != nil || address.City != "Rio de Janeiro" {
+		t.Errorf("LookupAddress() after restart = %+v, %v", address, err)
+	}
+	if _, err := svc.LookupAddress(context.Background(), "90541155"); !errors.Is(err, utils.ErrNotFound) {
+		t.Errorf("LookupAddress() after restart returned %v, expected not found", err)
+	}
+	if found.calls != 1 || missing.calls != 1 {
+		t.Errorf("providers called %d and %d times, expected once each", found.calls, missing.calls)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cepRangesCSV is the table of CEP ranges (faixas de CEP) the Correios
+// assign to each state. Some states own more than one range.
+//
+//go:embed cep_ranges.csv
+var cepRangesCSV string
+
+// CepRange is a range of CEPs that belongs to a single state.
+type CepRange struct {
+	UF     string
+	State  string
+	Region string
+	Start  int
+	End    int
+}
+
+// Contains reports whether the 8 digit cep n falls inside r.
+func (r CepRange) Contains(n int) bool {
+	return n >= r.Start && n <= r.End
+}
+
+// cepRanges is sorted by Start and has no overlaps.
+var cepRanges = parseCepRanges(cepRangesCSV)
+
+func parseCepRanges(data string) []CepRange {
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("cep ranges: %v", err))
+	}
+	ranges := make([]CepRange, 0, len(rows))
+	for _, row := range rows[1:] {
+		start, err := strconv.Atoi(row[3])
+		if err != nil {
+			panic(fmt.Sprintf("cep ranges: %v", err))
+		}
+		end, err := strconv.Atoi(row[4])
+		if err != nil {
+			panic(fmt.Sprintf("cep ranges: %v", err))
+		}
+		ranges = append(ranges, CepRange{UF: row[0], State: row[1], Region: row[2], Start: start, End: end})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Start <= ranges[i-1].End {
+			panic(fmt.Sprintf("cep ranges: %s overlaps %s", ranges[i].UF, ranges[i-1].UF))
+		}
+	}
+	return ranges
+}
+
+// CepRanges returns a copy of the range table, sorted by CEP.
+func CepRanges() []CepRange {
+	return append([]CepRange(nil), cepRanges...)
+}
+
+// RangeOfCep returns the range cep belongs to. ok is false when cep is not 8
+// digits or belongs to no state, e.g. 00000000.
+func RangeOfCep(cep string) (r CepRange, ok bool) {
+	cep = strings.ReplaceAll(cep, "-", "")
+	if len(cep) != 8 {
+		return CepRange{}, false
+	}
+	n, err := strconv.Atoi(cep)
+	if err != nil || n < 0 {
+		return CepRange{}, false
+	}
+	i := sort.Search(len(cepRanges), func(i int) bool { return cepRanges[i].End >= n })
+	if i == len(cepRanges) || !cepRanges[i].Contains(n) {
+		return CepRange{}, false
+	}
+	return cepRanges[i], true
+}
+
+// UFOfCep returns the state (UF) cep belongs to, or "" when it belongs to none.
+func UFOfCep(cep string) string {
+	r, _ := RangeOfCep(cep)
+	return r.UF
+}
+
+// IsUF reports whether uf, in any case, is the abbreviation of a state.
+func IsUF(uf string) bool {
+	_, _, ok := StateOfUF(uf)
+	return ok
+}
+
+// StateOfUF returns the full name and the region of the state uf, in any case.
+func StateOfUF(uf string) (name string, region string, ok bool) {
+	uf = strings.ToUpper(strings.TrimSpace(uf))
+	for _, r := range cepRanges {
+		if r.UF == uf {
+			return r.State, r.Region, true
+		}
+	}
+	return "", "", false
+}
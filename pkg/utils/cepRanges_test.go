@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestUFOfCep(t *testing.T) {
+	cases := map[string]string{
+		"01001000":  "SP",
+		"20541-155": "RJ",
+		"69301000":  "RR",
+		"69900000":  "AC",
+		"73010000":  "DF",
+		"74000000":  "GO",
+		"99999999":  "RS",
+		"00000000":  "",
+		"00999999":  "",
+		"2054115":   "",
+	}
+	for cep, uf := range cases {
+		if got := UFOfCep(cep); got != uf {
+			t.Errorf("UFOfCep(%q) = %q, expected %q", cep, got, uf)
+		}
+	}
+	if r, ok := RangeOfCep("20541155"); !ok || r.State != "Rio de Janeiro" || r.Region != "Sudeste" {
+		t.Errorf("RangeOfCep() = %+v, %v", r, ok)
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ProblemDetailer is implemented by errors that know which HTTP status they
+// map to and which stable, machine readable code identifies them.
+type ProblemDetailer interface {
+	HTTPStatus() int
+	ProblemCode() string
+}
+
+// ProviderNamer is implemented by errors that carry the provider that failed.
+type ProviderNamer interface {
+	ProviderName() string
+}
+
+// RetryAfterer is implemented by errors that know when the request is worth
+// repeating, e.g. a rate limit.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// Problem is an RFC 7807 problem document with a few extension members.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is stable and meant for programs, Detail is meant for humans.
+	Code     string `json:"code"`
+	Cep      string `json:"cep,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	// RetryAfter is in seconds and is also sent as the Retry-After header.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+const problemTypePrefix = "urn:tempbycep:problem:"
+
+// NewProblem describes err as a Problem. Errors that do not implement
+// ProblemDetailer are described by their error kind, and errors of no known
+// kind become a 500 internal_error.
+func NewProblem(err error) Problem {
+	p := Problem{
+		Status: http.StatusInternalServerError,
+		Code:   "internal_error",
+		Detail: err.Error(),
+	}
+
+	var detailer ProblemDetailer
+	if errors.As(err, &detailer) {
+		p.Status = detailer.HTTPStatus()
+		p.Code = detailer.ProblemCode()
+	} else if status, code, ok := KindStatus(err); ok {
+		p.Status = status
+		p.Code = code
+	}
+	var namer ProviderNamer
+	if errors.As(err, &namer) {
+		p.Provider = namer.ProviderName()
+	}
+	var retry RetryAfterer
+	if errors.As(err, &retry) && retry.RetryAfter() > 0 {
+		p.RetryAfter = int(math.Ceil(retry.RetryAfter().Seconds()))
+	}
+
+	p.Type = problemTypePrefix + p.Code
+	p.Title = http.StatusText(p.Status)
+	return p
+}
+
+// Write renders p as application/problem+json.
+func (p Problem) Write(w http.ResponseWriter) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(p.RetryAfter))
+	}
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
@@ -12,9 +12,49 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
+// Error kinds. Errors produced by this module wrap one of them so callers can
+// classify a failure with errors.Is instead of matching strings.
+var (
+	ErrInvalidCep          = errors.New("invalid cep")
+	ErrNotFound            = errors.New("not found")
+	ErrTimeout             = errors.New("timeout")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrInvalidQuery        = errors.New("invalid query")
+)
+
+// kinds maps each error kind to the status and problem code it renders as.
+var kinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrInvalidCep, http.StatusUnprocessableEntity, "invalid_cep"},
+	{ErrInvalidQuery, http.StatusBadRequest, "invalid_query"},
+	{ErrNotFound, http.StatusNotFound, "not_found"},
+	{ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+	{ErrRateLimited, http.StatusTooManyRequests, "rate_limited"},
+	{ErrUpstreamUnavailable, http.StatusBadGateway, "upstream_unavailable"},
+}
+
+// KindStatus returns the HTTP status and problem code of the error kind err
+// belongs to. ok is false when err wraps none of them.
+func KindStatus(err error) (status int, code string, ok bool) {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.status, k.code, true
+		}
+	}
+	return 0, "", false
+}
+
 type HttpError struct {
 	Code    int
 	Message string
+	// Kind is a stable machine readable identifier, used as the problem code.
+	Kind string
+	// Err is the error kind (one of the Err* sentinels) or cause, see Unwrap.
+	Err error
 }
 
 // Error implements error.
@@ -22,25 +62,91 @@ func (e HttpError) Error() string {
 	return strings.Join([]string{fmt.Sprint(e.Code), e.Message}, " ")
 }
 
+func (e HttpError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches another HttpError with the same status and kind, regardless of
+// the message, e.g. errors.Is(err, utils.ZipNotFoundError).
+func (e HttpError) Is(target error) bool {
+	t, ok := target.(HttpError)
+	return ok && t.Code == e.Code && t.Kind == e.Kind
+}
+
+// WithMessage returns a copy of e with a more specific message.
+func (e HttpError) WithMessage(msg string) HttpError {
+	e.Message = msg
+	return e
+}
+
+// HTTPStatus implements ProblemDetailer.
+func (e HttpError) HTTPStatus() int {
+	return e.Code
+}
+
+// ProblemCode implements ProblemDetailer.
+func (e HttpError) ProblemCode() string {
+	if e.Kind == "" {
+		return "http_" + fmt.Sprint(e.Code)
+	}
+	return e.Kind
+}
+
 var InvalidZipError = HttpError{
 	Code:    http.StatusUnprocessableEntity,
 	Message: "invalid zipcode",
+	Kind:    "invalid_cep",
+	Err:     ErrInvalidCep,
+}
+
+var InvalidSearchError = HttpError{
+	Code:    http.StatusBadRequest,
+	Message: "invalid address search",
+	Kind:    "invalid_search",
+	Err:     ErrInvalidQuery,
 }
 
 var ZipNotFoundError = HttpError{
 	Code:    http.StatusNotFound,
 	Message: "can not find zipcode",
+	Kind:    "cep_not_found",
+	Err:     ErrNotFound,
+}
+
+var TimeoutError = HttpError{
+	Code:    http.StatusGatewayTimeout,
+	Message: "upstream timeout",
+	Kind:    "timeout",
+	Err:     ErrTimeout,
+}
+
+var UpstreamError = HttpError{
+	Code:    http.StatusBadGateway,
+	Message: "upstream unavailable",
+	Kind:    "upstream_unavailable",
+	Err:     ErrUpstreamUnavailable,
+}
+
+var RateLimitedError = HttpError{
+	Code:    http.StatusTooManyRequests,
+	Message: "rate limited",
+	Kind:    "rate_limited",
+	Err:     ErrRateLimited,
 }
 
 func ValidateCep(cep string) error {
 	cep = strings.ReplaceAll(cep, "-", "")
 	if len(cep) != 8 {
-		return errors.New("cep must contain exactly 8 characters")
+		return fmt.Errorf("%w: cep must contain exactly 8 characters", ErrInvalidCep)
 	}
 
 	_, err := strconv.Atoi(cep)
 	if err != nil {
-		return errors.New("cep must contain only numbers")
+		return fmt.Errorf("%w: cep must contain only numbers", ErrInvalidCep)
+	}
+
+	if _, ok := RangeOfCep(cep); !ok {
+		return fmt.Errorf("%w: cep belongs to no state range", ErrInvalidCep)
 	}
 
 	return nil